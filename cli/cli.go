@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"io"
+	"os"
+)
+
+var commands = map[string]func([]string, io.Writer) int{
+	"verify":   Verify,
+	"optimize": Optimize,
+}
+
+// Run executes the subcommand named by the first argument and exits. It
+// returns false if args do not name a known subcommand.
+func Run(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return false
+	}
+	os.Exit(cmd(args[1:], os.Stdout))
+	return true
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/x-cellent/gokoban/gokoban"
+	"github.com/x-cellent/gokoban/profile"
+	"github.com/x-cellent/gokoban/solver"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Optimize shortens a solution and stores it if a better one is found. The
+// solution is read from a solution file as written by Level.WriteSolution,
+// which is rewritten, or else taken from the best solutions in the player's
+// profile, which records the improvement. It returns 0 on success, 1 if the
+// solution is invalid and 2 on usage or load errors.
+func Optimize(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	fs.SetOutput(out)
+	timeout := fs.Duration("timeout", 30*time.Second, "maximum time spent optimizing")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(out, "usage: gokoban optimize [-timeout duration] level-file [solution-file]")
+		_, _ = fmt.Fprintln(out, "Without a solution file the best solution in the profile is optimized.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	levelFile := fs.Arg(0)
+	level, err := gokoban.LoadLevelFS(os.DirFS(filepath.Dir(levelFile)), filepath.Base(levelFile))
+	if err != nil {
+		_, _ = fmt.Fprintln(out, err)
+		return 2
+	}
+
+	var store func([]gokoban.Course) error
+	if fs.NArg() == 2 {
+		store, err = loadSolutionFile(level, fs.Arg(1))
+	} else {
+		store, err = loadProfileSolution(level)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(out, err)
+		return 2
+	}
+
+	before := level.Verify()
+	if !before.Valid {
+		_, _ = fmt.Fprintf(out, "solution is invalid at step %d: %v\n", before.Step+1, before.Err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	solution, err := solver.Optimize(ctx, level, level.Solution)
+	if err != nil {
+		_, _ = fmt.Fprintln(out, err)
+		return 1
+	}
+
+	after := level.VerifySolution(solution)
+	if after.Moves > before.Moves || (after.Moves == before.Moves && after.Pushes >= before.Pushes) {
+		_, _ = fmt.Fprintf(out, "no improvement found (%d moves, %d pushes)\n", before.Moves, before.Pushes)
+		return 0
+	}
+
+	if err := store(solution); err != nil {
+		_, _ = fmt.Fprintln(out, err)
+		return 2
+	}
+
+	_, _ = fmt.Fprintf(out, "%d moves, %d pushes -> %d moves, %d pushes\n", before.Moves, before.Pushes, after.Moves, after.Pushes)
+	return 0
+}
+
+// loadSolutionFile sets the solution of level from file and returns how an
+// improved solution is written back to it.
+func loadSolutionFile(level *gokoban.Level, file string) (func([]gokoban.Course) error, error) {
+	if err := level.LoadSolutionFS(os.DirFS(filepath.Dir(file)), filepath.Base(file)); err != nil {
+		return nil, err
+	}
+	return func(solution []gokoban.Course) error {
+		level.SetSolution(solution)
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return level.WriteSolution(f)
+	}, nil
+}
+
+// loadProfileSolution sets the solution of level to the best one in the
+// player's profile and returns how an improved solution is added to it.
+func loadProfileSolution(level *gokoban.Level) (func([]gokoban.Course) error, error) {
+	dir, err := profile.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	p, err := profile.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	r, err := p.Record(level)
+	if err != nil {
+		return nil, err
+	}
+	if !r.Solved() {
+		return nil, fmt.Errorf("level is not solved in the profile")
+	}
+	if err := level.LoadSolution(strings.NewReader(r.BestMoves.LURD)); err != nil {
+		return nil, err
+	}
+	return func(solution []gokoban.Course) error {
+		for _, course := range solution {
+			level.Move(course)
+		}
+		_, _, err := p.AddSolution(level, r.LastSolved)
+		return err
+	}, nil
+}
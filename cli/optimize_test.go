@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"github.com/x-cellent/gokoban/gokoban"
+	"github.com/x-cellent/gokoban/profile"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptimizeSolutionFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		solution     string
+		want         int
+		wantOutput   string
+		wantSolution string
+	}{
+		{name: "improved", solution: "rlrRR", want: 0, wantOutput: "5 moves, 2 pushes -> 3 moves, 2 pushes\n", wantSolution: "rRR"},
+		{name: "optimal", solution: "rRR", want: 0, wantOutput: "no improvement found (3 moves, 2 pushes)\n", wantSolution: "rRR"},
+		{name: "invalid", solution: "rR", want: 1, wantOutput: "solution is invalid at step 3", wantSolution: "rR"},
+		{name: "bad character", solution: "rRx", want: 2, wantOutput: "row 1, column 3", wantSolution: "rRx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levelFile := writeFile(t, "level.txt", corridor)
+			solutionFile := writeFile(t, "solution.txt", tt.solution)
+			var out bytes.Buffer
+			if got := Optimize([]string{levelFile, solutionFile}, &out); got != tt.want {
+				t.Errorf("Optimize() = %d, want %d, output:\n%s", got, tt.want, out.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("Optimize() output = %q, want it to contain %q", out.String(), tt.wantOutput)
+			}
+			if bb, err := ioutil.ReadFile(solutionFile); err != nil || string(bb) != tt.wantSolution {
+				t.Errorf("solution file = %q, %v, want %q", bb, err, tt.wantSolution)
+			}
+		})
+	}
+}
+
+func TestOptimizeProfile(t *testing.T) {
+	home := t.TempDir()
+	for _, key := range []string{"HOME", "XDG_CONFIG_HOME", "AppData"} {
+		old, ok := os.LookupEnv(key)
+		if err := os.Setenv(key, home); err != nil {
+			t.Fatal(err)
+		}
+		defer func(key string) {
+			if ok {
+				_ = os.Setenv(key, old)
+			} else {
+				_ = os.Unsetenv(key)
+			}
+		}(key)
+	}
+	levelFile := writeFile(t, "level.txt", corridor)
+
+	var out bytes.Buffer
+	if got := Optimize([]string{levelFile}, &out); got != 2 {
+		t.Errorf("Optimize() of an unsolved level = %d, want 2, output:\n%s", got, out.String())
+	}
+
+	dir, err := profile.DefaultDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := profile.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := gokoban.LoadLevel(strings.NewReader(corridor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []gokoban.Course{gokoban.Right, gokoban.Left, gokoban.Right, gokoban.Right, gokoban.Right} {
+		l.Move(c)
+	}
+	if _, _, err := p.AddSolution(l, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if got := Optimize([]string{levelFile}, &out); got != 0 {
+		t.Errorf("Optimize() = %d, want 0, output:\n%s", got, out.String())
+	}
+	r, err := p.Record(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.BestMoves.LURD, "rRR"; got != want {
+		t.Errorf("best moves in profile = %q, want %q", got, want)
+	}
+}
+
+func TestOptimizeUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no arguments"},
+		{name: "too many arguments", args: []string{"a", "b", "c"}},
+		{name: "missing level", args: []string{"missing.txt", "solution.txt"}},
+		{name: "bad timeout", args: []string{"-timeout", "soon", "level.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if got := Optimize(tt.args, &out); got != 2 {
+				t.Errorf("Optimize() = %d, want 2, output:\n%s", got, out.String())
+			}
+		})
+	}
+}
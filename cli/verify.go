@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/x-cellent/gokoban/gokoban"
+	"io"
+)
+
+const defaultLevelDir = "gokoban/levels"
+
+// Verify replays the stored solution of every level in a collection and
+// returns the process exit code: 0 if all solutions are valid, 1 if any
+// solution fails and 2 on usage or load errors.
+func Verify(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(out, "usage: gokoban verify [level directory or collection file]")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	path := defaultLevelDir
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	collection, err := gokoban.OpenCollection(path)
+	if collection == nil {
+		_, _ = fmt.Fprintln(out, err)
+		return 2
+	}
+
+	// Levels keep their numbers in the file, invalid ones were skipped.
+	var problems gokoban.CollectionErrors
+	errors.As(err, &problems)
+	skipped := make(map[int]error)
+	for _, p := range problems {
+		if p.Skipped {
+			skipped[p.Level] = p.Err
+		}
+	}
+
+	failed := len(skipped)
+	n := 0
+	reportSkipped := func() {
+		for skipped[n+1] != nil {
+			n++
+			_, _ = fmt.Fprintf(out, "level %d: FAILED: %v\n", n, skipped[n])
+		}
+	}
+	for _, level := range collection.Levels {
+		reportSkipped()
+		n++
+		v := level.Verify()
+		if v.Valid {
+			_, _ = fmt.Fprintf(out, "level %d: ok (%d moves, %d pushes)\n", n, v.Moves, v.Pushes)
+			continue
+		}
+		failed++
+		if v.Err == gokoban.ErrNoSolution {
+			reason := v.Err
+			for _, p := range problems {
+				if p.Level == n {
+					reason = p.Err
+				}
+			}
+			_, _ = fmt.Fprintf(out, "level %d: FAILED: %v\n", n, reason)
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "level %d: FAILED at step %d: %v (%d moves, %d pushes)\n", n, v.Step+1, v.Err, v.Moves, v.Pushes)
+	}
+
+	reportSkipped()
+
+	total := len(collection.Levels) + len(skipped)
+	_, _ = fmt.Fprintf(out, "%d of %d solutions valid\n", total-failed, total)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
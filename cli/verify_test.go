@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const corridor = "#######\n#@ $ .#\n#######\n"
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+		args       []string
+		want       int
+		wantOutput string
+	}{
+		{
+			name:       "valid",
+			collection: corridor + "Solution: rRR\n\n" + corridor + "Solution: rlrRR\n",
+			want:       0,
+			wantOutput: "level 2: ok (5 moves, 2 pushes)\n2 of 2 solutions valid\n",
+		},
+		{
+			name:       "illegal move",
+			collection: corridor + "Solution: rRRR\n",
+			want:       1,
+			wantOutput: "level 1: FAILED at step 4: illegal move (3 moves, 2 pushes)\n0 of 1 solutions valid\n",
+		},
+		{
+			name:       "not completed",
+			collection: corridor + "Solution: rR\n",
+			want:       1,
+			wantOutput: "level 1: FAILED at step 3: solution does not complete the level (2 moves, 1 pushes)\n",
+		},
+		{
+			name:       "no solution",
+			collection: corridor,
+			want:       1,
+			wantOutput: "level 1: FAILED: level has no solution\n",
+		},
+		{
+			name:       "invalid level",
+			collection: corridor + "Solution: rRR\n\n#####\n#@ .#\n#####\n\n" + corridor + "Solution: rRR\n",
+			want:       1,
+			wantOutput: "level 1: ok (3 moves, 2 pushes)\nlevel 2: FAILED: level has no boxes; number of boxes and targets differ\nlevel 3: ok (3 moves, 2 pushes)\n2 of 3 solutions valid\n",
+		},
+		{
+			name:       "invalid solution",
+			collection: corridor + "Solution: rRx\n",
+			want:       1,
+			wantOutput: "level 1: FAILED: bad solution character at row 1, column 3\n",
+		},
+		{name: "no valid level", collection: "#####\n#@ .#\n#####\n", want: 2},
+		{name: "missing file", args: []string{"missing.sok"}, want: 2},
+		{name: "unknown flag", args: []string{"-x"}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.collection != "" {
+				args = []string{writeFile(t, "levels.sok", tt.collection)}
+			}
+			var out bytes.Buffer
+			if got := Verify(args, &out); got != tt.want {
+				t.Errorf("Verify() = %d, want %d, output:\n%s", got, tt.want, out.String())
+			}
+			if !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("Verify() output =\n%s\nwant it to contain\n%s", out.String(), tt.wantOutput)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
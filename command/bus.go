@@ -19,5 +19,7 @@ func createCommandDefinitions() []*decs.CommandDefinition {
 		newMoveRight(),
 		newMoveDown(),
 		newMoveLeft(),
+		newFollowPath(),
+		newRedo(),
 	}
 }
@@ -0,0 +1,25 @@
+package command
+
+import (
+	"github.com/x-cellent/decs"
+	"github.com/x-cellent/gokoban/event"
+	"github.com/x-cellent/gokoban/gokoban"
+)
+
+const FollowPath = "followPath"
+
+type FollowPathData struct {
+	Path []gokoban.Course
+}
+
+func NewFollowPath(path []gokoban.Course) decs.Command {
+	return Bus.NewCommand(FollowPath, &FollowPathData{Path: path})
+}
+
+func newFollowPath() *decs.CommandDefinition {
+	return &decs.CommandDefinition{
+		Name:         FollowPath,
+		DataType:     &FollowPathData{},
+		UndoneEvents: []string{event.OnFollowPathUndone},
+	}
+}
@@ -0,0 +1,19 @@
+package command
+
+import (
+	"github.com/x-cellent/decs"
+	"github.com/x-cellent/gokoban/event"
+)
+
+const Redo = "redo"
+
+func NewRedo() decs.Command {
+	return Bus.NewCommand(Redo, nil)
+}
+
+func newRedo() *decs.CommandDefinition {
+	return &decs.CommandDefinition{
+		Name:         Redo,
+		UndoneEvents: []string{event.OnRedoUndone},
+	}
+}
@@ -1,13 +1,18 @@
 package console
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"github.com/jroimartin/gocui"
 	"github.com/x-cellent/decs"
 	"github.com/x-cellent/gokoban/command"
 	"github.com/x-cellent/gokoban/event"
 	"github.com/x-cellent/gokoban/gokoban"
+	"github.com/x-cellent/gokoban/profile"
 	"log"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -22,15 +27,26 @@ var (
 	natsURL         = flag.String("n", "", "NATS URL")
 	natsClusterID   = flag.String("c", "", "NATS cluster ID")
 	natsClientID    = flag.String("C", "", "NATS client ID")
+	collectionFile  = flag.String("f", "", "Sokoban collection file (.xsb, .sok)")
+	themeName       = flag.String("theme", "", "Theme: classic, modern, mono or one from themes.json")
+	colorMode       = flag.String("colors", "", "Colors: none, 16, 256 or truecolor (default: detected)")
+	wideCells       = flag.Bool("wide", false, "Draw every field two columns wide")
 )
 
+const levelDir = "gokoban/levels"
+
 func init() {
 	flag.StringVar(nsqdTcpAddress, "nsqd-tcp-address", "", "NSQD TCP address")
 	flag.StringVar(nsqdHttpAddress, "nsqd-http-address", "", "NSQD HTTP address")
 	flag.StringVar(natsURL, "nats-url", "", "NATS URL")
 	flag.StringVar(natsClusterID, "nats-cluster-id", "", "NATS cluster ID")
 	flag.StringVar(natsClientID, "nats-client-id", "", "NATS client ID")
+	flag.StringVar(collectionFile, "file", "", "Sokoban collection file (.xsb, .sok)")
+}
 
+// configureCommandBus parses the command line and connects the command bus
+// to the message broker given there, if any.
+func configureCommandBus() {
 	flag.Parse()
 
 	if len(*nsqdTcpAddress) > 0 {
@@ -51,38 +67,145 @@ func init() {
 }
 
 func Run() {
-	gui := gocui.NewGui()
+	configureCommandBus()
+
+	depth := detectColorDepth()
+	if len(*colorMode) > 0 {
+		d, err := parseColorDepth(*colorMode)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		depth = d
+	}
+	depth = depth.supported()
+	theme, err := loadTheme(*themeName, depth)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	gui, err := depth.newGui()
+	if err != nil {
+		log.Panicln(err)
+	}
 	defer func() {
 		gui.Cursor = true
 		gui.Close()
 	}()
 
-	err := gui.Init()
+	gui.Cursor = true
+
+	saved, err := loadSession(sessionFile)
 	if err != nil {
-		log.Panicln(err)
+		log.Println(err)
 	}
 
-	gui.Cursor = true
+	path := levelDir
+	if len(*collectionFile) > 0 {
+		path = *collectionFile
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		log.Panicln(err)
+	}
+	collection, collectionErr := gokoban.OpenCollection(path)
+	if collection == nil {
+		log.Panicln(collectionErr)
+	}
 
-	game := newGame("gokoban/levels", 1, gui)
+	game := newGame(path, collection, 1, gui)
+	game.theme = theme
+	game.wide = *wideCells
+	if dir, err := profile.DefaultDir(); err == nil {
+		game.profile, err = profile.Open(dir)
+		if err != nil {
+			log.Println(err)
+		}
+	}
 
-	gui.SetLayout(game.layout)
+	gui.SetManagerFunc(game.layout)
 
 	gui.Cursor = false
+	gui.Mouse = true
+	gui.InputEsc = true
 
 	initiateCommandBus(game)
 
+	keys, keysErr := loadKeyMap(keysFile)
+	if keysErr == nil {
+		game.keys = keys
+	}
 	if err := game.keyBindings(); err != nil {
 		log.Panicln(err)
 	}
 
-	game.loadLevel()
+	game.openLevel()
+	var warnings []string
+	if collectionErr != nil {
+		warnings = append(warnings, skippedWarning(collectionErr))
+	}
+	if keysErr != nil {
+		warnings = append(warnings, fmt.Sprintf("Key bindings not loaded: %v", keysErr))
+	}
+	if len(warnings) > 0 {
+		game.flashWarning(strings.Join(warnings, "; "))
+		game.update()
+	}
+	restored := false
+	if saved != nil && (saved.Collection == path || len(*collectionFile) == 0) {
+		if err := game.restore(saved); err != nil {
+			game.flashWarning(fmt.Sprintf("Session not restored: %v", err))
+			game.update()
+		} else {
+			restored = true
+		}
+	}
+	if !restored {
+		game.addAttempt()
+		game.update()
+	}
 
 	if err := gui.MainLoop(); err != nil && err != gocui.ErrQuit {
 		log.Panicln(err)
 	}
 }
 
+// skippedWarning summarizes the problems of a collection that was loaded
+// without some of its levels or solutions.
+func skippedWarning(err error) string {
+	var problems gokoban.CollectionErrors
+	if !errors.As(err, &problems) || len(problems) == 0 {
+		return err.Error()
+	}
+	levels, solutions := 0, 0
+	for _, p := range problems {
+		if p.Skipped {
+			levels++
+		} else {
+			solutions++
+		}
+	}
+	var skipped []string
+	if levels > 0 {
+		skipped = append(skipped, fmt.Sprintf("%d invalid levels", levels))
+	}
+	if solutions > 0 {
+		skipped = append(skipped, fmt.Sprintf("%d invalid solutions", solutions))
+	}
+	return fmt.Sprintf("Skipped %s, first %v", strings.Join(skipped, " and "), problems[0])
+}
+
+func newMoveCommand(course gokoban.Course) decs.Command {
+	switch course {
+	case gokoban.Up:
+		return command.NewMoveUp()
+	case gokoban.Right:
+		return command.NewMoveRight()
+	case gokoban.Down:
+		return command.NewMoveDown()
+	default:
+		return command.NewMoveLeft()
+	}
+}
+
 func initiateCommandBus(g *game) {
 	command.InitiateBus()
 
@@ -103,6 +226,17 @@ func initiateCommandBus(g *game) {
 		notifier.NotifySuccess(event.OnMovedLeft, nil)
 	})
 
+	command.Bus.RegisterCommandHandler(command.FollowPath, func(cmd decs.Command, delegate decs.Delegate, notifier decs.ResultNotifier) {
+		g.followPath(cmd.Data().(*command.FollowPathData).Path)
+		notifier.NotifySuccess(event.OnPathFollowed, nil)
+	})
+	command.Bus.RegisterCommandHandler(command.Redo, func(cmd decs.Command, delegate decs.Delegate, notifier decs.ResultNotifier) {
+		g.move(func() {
+			g.level.Redo()
+		})
+		notifier.NotifySuccess(event.OnRedone, nil)
+	})
+
 	command.Bus.SubscribeAfter(decs.PurgeApplication, func(data interface{}, dispatcher decs.EventDispatcher) {
 		g.reset()
 	})
@@ -119,6 +253,12 @@ func initiateCommandBus(g *game) {
 	command.Bus.SubscribeAfterSuccess(event.OnMovedLeft, func(data interface{}, dispatcher decs.EventDispatcher) {
 		g.update()
 	})
+	command.Bus.SubscribeAfterSuccess(event.OnPathFollowed, func(data interface{}, dispatcher decs.EventDispatcher) {
+		g.update()
+	})
+	command.Bus.SubscribeAfterSuccess(event.OnRedone, func(data interface{}, dispatcher decs.EventDispatcher) {
+		g.update()
+	})
 
 	command.Bus.RegisterUndoHandler(command.MoveUp, func(cmd decs.Command, delegate decs.Delegate) {
 		g.undoLastMove()
@@ -132,4 +272,12 @@ func initiateCommandBus(g *game) {
 	command.Bus.RegisterUndoHandler(command.MoveLeft, func(cmd decs.Command, delegate decs.Delegate) {
 		g.undoLastMove()
 	})
+	command.Bus.RegisterUndoHandler(command.Redo, func(cmd decs.Command, delegate decs.Delegate) {
+		g.undoLastMove()
+	})
+	command.Bus.RegisterUndoHandler(command.FollowPath, func(cmd decs.Command, delegate decs.Delegate) {
+		for range cmd.Data().(*command.FollowPathData).Path {
+			g.undoLastMove()
+		}
+	})
 }
@@ -0,0 +1,507 @@
+package console
+
+import (
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/x-cellent/gokoban/command"
+	"github.com/x-cellent/gokoban/gokoban"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	editorView = "editor"
+	// editorIndent is the column of the canvas inside the editor view.
+	editorIndent = 2
+
+	minEditorSize   = 3
+	maxEditorWidth  = 80
+	maxEditorHeight = 40
+	newEditorWidth  = 10
+	newEditorHeight = 7
+)
+
+// editor holds the canvas of the level editor. The canvas is validated
+// after every change, test is the loaded level if it is valid. level,
+// forward and rotation are the game state to return to once the editor is
+// closed.
+type editor struct {
+	width    int
+	height   int
+	cells    []byte
+	cursor   gokoban.Position
+	test     *gokoban.Level
+	err      error
+	problems map[gokoban.Position]bool
+	testing  bool
+
+	level    *gokoban.Level
+	forward  *gokoban.Level
+	rotation int
+}
+
+// newEditor copies the initial position of l onto the canvas.
+func newEditor(l *gokoban.Level) *editor {
+	e := &editor{
+		width:  l.Width(),
+		height: l.Height(),
+		cells:  make([]byte, l.Width()*l.Height()),
+	}
+	pc, pr := l.InitialPlayerPosition()
+	for r := 0; r < e.height; r++ {
+		for c := 0; c < e.width; c++ {
+			symbol := gokoban.FreeSymbol
+			switch {
+			case l.IsBrick(c, r):
+				symbol = gokoban.BrickSymbol
+			case c == pc && r == pr && l.IsTarget(c, r):
+				symbol = gokoban.PlayerOnTargetSymbol
+			case c == pc && r == pr:
+				symbol = gokoban.PlayerSymbol
+			case l.IsInitialBox(c, r) && l.IsTarget(c, r):
+				symbol = gokoban.BoxOnTargetSymbol
+			case l.IsInitialBox(c, r):
+				symbol = gokoban.BoxSymbol
+			case l.IsTarget(c, r):
+				symbol = gokoban.TargetSymbol
+			}
+			e.cells[r*e.width+c] = symbol[0]
+		}
+	}
+	e.validate()
+	return e
+}
+
+// clear replaces the canvas by an empty room surrounded by bricks.
+func (e *editor) clear(width, height int) {
+	e.width, e.height = width, height
+	e.cells = make([]byte, width*height)
+	for pos := range e.cells {
+		c, r := pos%width, pos/width
+		if c == 0 || r == 0 || c == width-1 || r == height-1 {
+			e.cells[pos] = gokoban.BrickSymbol[0]
+		} else {
+			e.cells[pos] = gokoban.FreeSymbol[0]
+		}
+	}
+	e.cursor = gokoban.Position{Col: 1, Row: 1}
+	e.validate()
+}
+
+func (e *editor) symbol(c, r int) byte {
+	return e.cells[r*e.width+c]
+}
+
+// place puts symbol under the cursor. Boxes and the player keep a target
+// they are placed on and vice versa, there is only ever one player.
+func (e *editor) place(symbol byte) {
+	pos := e.cursor.Row*e.width + e.cursor.Col
+	onTarget := strings.IndexByte(".*+", e.cells[pos]) >= 0
+
+	switch symbol {
+	case gokoban.PlayerSymbol[0], gokoban.PlayerOnTargetSymbol[0]:
+		for p, s := range e.cells {
+			switch s {
+			case gokoban.PlayerSymbol[0]:
+				e.cells[p] = gokoban.FreeSymbol[0]
+			case gokoban.PlayerOnTargetSymbol[0]:
+				e.cells[p] = gokoban.TargetSymbol[0]
+			}
+		}
+		if onTarget || symbol == gokoban.PlayerOnTargetSymbol[0] {
+			symbol = gokoban.PlayerOnTargetSymbol[0]
+		}
+	case gokoban.BoxSymbol[0]:
+		if onTarget {
+			symbol = gokoban.BoxOnTargetSymbol[0]
+		}
+	case gokoban.TargetSymbol[0]:
+		switch e.cells[pos] {
+		case gokoban.BoxSymbol[0], gokoban.BoxOnTargetSymbol[0]:
+			symbol = gokoban.BoxOnTargetSymbol[0]
+		case gokoban.PlayerSymbol[0], gokoban.PlayerOnTargetSymbol[0]:
+			symbol = gokoban.PlayerOnTargetSymbol[0]
+		}
+	}
+	e.cells[pos] = symbol
+	e.validate()
+}
+
+// resize changes the canvas size, keeping the upper left corner.
+func (e *editor) resize(width, height int) {
+	if width < minEditorSize || height < minEditorSize || width > maxEditorWidth || height > maxEditorHeight {
+		return
+	}
+	cells := make([]byte, width*height)
+	for pos := range cells {
+		c, r := pos%width, pos/width
+		if c < e.width && r < e.height {
+			cells[pos] = e.symbol(c, r)
+		} else {
+			cells[pos] = gokoban.FreeSymbol[0]
+		}
+	}
+	e.width, e.height, e.cells = width, height, cells
+	if e.cursor.Col >= width {
+		e.cursor.Col = width - 1
+	}
+	if e.cursor.Row >= height {
+		e.cursor.Row = height - 1
+	}
+	e.validate()
+}
+
+func (e *editor) moveCursor(course gokoban.Course) {
+	p := e.cursor.Neighbor(course)
+	if p.Col >= 0 && p.Row >= 0 && p.Col < e.width && p.Row < e.height {
+		e.cursor = p
+	}
+}
+
+// lines returns the canvas in the level file format. Blank lines are left
+// out, just like the level loader does.
+func (e *editor) lines() (lines []string, rows []int) {
+	for r := 0; r < e.height; r++ {
+		line := strings.TrimRight(string(e.cells[r*e.width:(r+1)*e.width]), gokoban.FreeSymbol)
+		if len(line) == 0 {
+			continue
+		}
+		lines = append(lines, line)
+		rows = append(rows, r)
+	}
+	return lines, rows
+}
+
+func (e *editor) String() string {
+	lines, _ := e.lines()
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// validate loads the canvas as a level and marks the fields of all
+// problems found.
+func (e *editor) validate() {
+	e.test, e.err, e.problems = nil, nil, make(map[gokoban.Position]bool)
+	_, rows := e.lines()
+	l, err := gokoban.LoadLevel(strings.NewReader(e.String()))
+	if err == nil {
+		e.test = l
+		return
+	}
+	e.err = err
+	if problems, ok := err.(gokoban.Problems); ok {
+		for _, p := range problems {
+			if p.Col >= 0 && p.Row >= 0 && p.Row < len(rows) {
+				e.problems[gokoban.Position{Col: p.Col, Row: rows[p.Row]}] = true
+			}
+		}
+	}
+}
+
+// save writes the canvas into dir as the level following the last one.
+func (e *editor) save(dir string) (string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	max := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "level") || !strings.HasSuffix(name, ".txt") {
+			continue
+		}
+		if lvl, err := strconv.Atoi(name[5 : len(name)-4]); err == nil && max < lvl {
+			max = lvl
+		}
+	}
+
+	name := fmt.Sprintf("level%d.txt", max+1)
+	return name, ioutil.WriteFile(filepath.Join(dir, name), []byte(e.String()), 0644)
+}
+
+func (g *game) editing() bool {
+	return g.editor != nil && !g.editor.testing
+}
+
+func (g *game) playTesting() bool {
+	return g.editor != nil && g.editor.testing
+}
+
+// editHandler opens the editor with the current level, or returns to it
+// from a play-test.
+func (g *game) editHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() {
+		return nil
+	}
+	if v != nil {
+		if g.playTesting() {
+			g.editLevel()
+		} else if !g.level.Completed() {
+			g.openEditor()
+		}
+	}
+	return nil
+}
+
+func (g *game) editorMoveHandler(course gokoban.Course) func(*gocui.Gui, *gocui.View) error {
+	return func(gui *gocui.Gui, v *gocui.View) error {
+		if v != nil && g.editing() {
+			g.editor.moveCursor(course)
+		}
+		return nil
+	}
+}
+
+func (g *game) editorPlaceHandler(symbol string) func(*gocui.Gui, *gocui.View) error {
+	return func(gui *gocui.Gui, v *gocui.View) error {
+		if v != nil && g.editing() {
+			g.editor.place(symbol[0])
+		}
+		return nil
+	}
+}
+
+func (g *game) editorResizeHandler(dw, dh int) func(*gocui.Gui, *gocui.View) error {
+	return func(gui *gocui.Gui, v *gocui.View) error {
+		if v != nil && g.editing() {
+			g.editor.resize(g.editor.width+dw, g.editor.height+dh)
+		}
+		return nil
+	}
+}
+
+func (g *game) editorNewHandler(gui *gocui.Gui, v *gocui.View) error {
+	if v != nil && g.editing() {
+		g.editor.clear(newEditorWidth, newEditorHeight)
+	}
+	return nil
+}
+
+func (g *game) editorTestHandler(gui *gocui.Gui, v *gocui.View) error {
+	if v != nil && g.editing() {
+		return g.playTest()
+	}
+	return nil
+}
+
+func (g *game) editorSaveHandler(gui *gocui.Gui, v *gocui.View) error {
+	if v != nil && g.editing() {
+		g.saveEditedLevel()
+	}
+	return nil
+}
+
+func (g *game) editorCloseHandler(gui *gocui.Gui, v *gocui.View) error {
+	if v != nil && g.editing() {
+		return g.closeEditor()
+	}
+	return nil
+}
+
+func (g *game) editorKeyBindings() error {
+	if err := g.bind(editorView, "up", g.editorMoveHandler(gokoban.Up)); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "right", g.editorMoveHandler(gokoban.Right)); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "down", g.editorMoveHandler(gokoban.Down)); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "left", g.editorMoveHandler(gokoban.Left)); err != nil {
+		return err
+	}
+	for _, symbol := range []string{
+		gokoban.BrickSymbol,
+		gokoban.TargetSymbol,
+		gokoban.BoxSymbol,
+		gokoban.BoxOnTargetSymbol,
+		gokoban.PlayerSymbol,
+		gokoban.PlayerOnTargetSymbol,
+	} {
+		if err := g.gui.SetKeybinding(editorView, rune(symbol[0]), gocui.ModNone, g.editorPlaceHandler(symbol)); err != nil {
+			return err
+		}
+	}
+	for _, key := range []gocui.Key{gocui.KeySpace, gocui.KeyDelete, gocui.KeyBackspace, gocui.KeyBackspace2} {
+		if err := g.gui.SetKeybinding(editorView, key, gocui.ModNone, g.editorPlaceHandler(gokoban.FreeSymbol)); err != nil {
+			return err
+		}
+	}
+	if err := g.bind(editorView, "narrower", g.editorResizeHandler(-1, 0)); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "wider", g.editorResizeHandler(1, 0)); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "shorter", g.editorResizeHandler(0, -1)); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "taller", g.editorResizeHandler(0, 1)); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "editorNew", g.editorNewHandler); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "editorTest", g.editorTestHandler); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "editorSave", g.editorSaveHandler); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "cancel", g.editorCloseHandler); err != nil {
+		return err
+	}
+	if err := g.bind(editorView, "edit", g.editorCloseHandler); err != nil {
+		return err
+	}
+	return nil
+}
+
+// openEditor starts editing a copy of the current level of the collection.
+func (g *game) openEditor() {
+	g.cursor = nil
+	g.editor = newEditor(g.collection.Levels[g.lvl-1])
+	g.editor.level, g.editor.forward, g.editor.rotation = g.level, g.forward, g.rotation
+	g.update()
+}
+
+// editLevel returns from a play-test to the editor.
+func (g *game) editLevel() {
+	g.replaySpeed = 0
+	g.cursor = nil
+	g.editor.testing = false
+	g.update()
+}
+
+// playTest plays a fresh copy of the edited level in the game view.
+func (g *game) playTest() error {
+	g.editor.validate()
+	if g.editor.test == nil {
+		return nil
+	}
+	g.editor.testing = true
+	if err := g.gui.DeleteView(editorView); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	if _, err := g.gui.SetCurrentView(g.view); err != nil {
+		return err
+	}
+	g.level, g.forward, g.rotation = g.editor.test, nil, 0
+	if err := g.layout(g.gui); err != nil {
+		return err
+	}
+	g.reset()
+	return nil
+}
+
+// closeEditor continues the game the editor was opened from.
+func (g *game) closeEditor() error {
+	g.level, g.forward, g.rotation = g.editor.level, g.editor.forward, g.editor.rotation
+	g.editor = nil
+	if err := g.gui.DeleteView(editorView); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	if _, err := g.gui.SetCurrentView(g.view); err != nil {
+		return err
+	}
+	command.Bus.Clear()
+	if err := g.layout(g.gui); err != nil {
+		return err
+	}
+	g.update()
+	return nil
+}
+
+// saveEditedLevel adds the edited level to the level directory, which is
+// the current collection if it is one.
+func (g *game) saveEditedLevel() {
+	if g.editor.test == nil {
+		g.flashWarning("Only valid levels can be saved")
+		return
+	}
+	dir := g.path
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = levelDir
+	}
+	name, err := g.editor.save(dir)
+	if err != nil {
+		g.flashWarning(fmt.Sprintf("Saving failed: %v", err))
+		return
+	}
+	if dir == g.path {
+		if collection, _ := gokoban.OpenCollection(g.path); collection != nil {
+			g.collection = collection
+		}
+	}
+	g.flashWarning(fmt.Sprintf("Saved as %s", filepath.Join(dir, name)))
+}
+
+// layoutEditor draws the editor on top of the game.
+func (g *game) layoutEditor(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	v, err := gui.SetView(editorView, 1, 1, maxX-2, maxY-2)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = " Level editor "
+		if _, err := gui.SetCurrentView(editorView); err != nil {
+			return err
+		}
+	}
+	v.Clear()
+	g.printEditor(v)
+	return nil
+}
+
+func (g *game) printEditor(view *gocui.View) {
+	e := g.editor
+	_, _ = fmt.Fprintf(view, "%s\n\n", gokoban.Indent(fmt.Sprintf("Size %dx%d, cursor at row %d, column %d", e.width, e.height, e.cursor.Row+1, e.cursor.Col+1), editorIndent))
+	for r := 0; r < e.height; r++ {
+		_, _ = fmt.Fprint(view, gokoban.Indent("", editorIndent))
+		for c := 0; c < e.width; c++ {
+			var overlays []string
+			if e.problems[gokoban.Position{Col: c, Row: r}] {
+				overlays = append(overlays, "problem")
+			}
+			if e.cursor.Col == c && e.cursor.Row == r {
+				overlays = append(overlays, "cursor")
+			}
+			_, _ = fmt.Fprint(view, g.theme.field(fieldStyle(string(e.symbol(c, r))), overlays...))
+		}
+		_, _ = fmt.Fprintln(view)
+	}
+	_, _ = fmt.Fprintln(view)
+
+	if e.err != nil {
+		if problems, ok := e.err.(gokoban.Problems); ok {
+			for _, p := range problems {
+				_, _ = fmt.Fprintln(view, g.theme.paint("warning", gokoban.Indent(p.Error(), editorIndent)))
+			}
+		} else {
+			_, _ = fmt.Fprintln(view, g.theme.paint("warning", gokoban.Indent(e.err.Error(), editorIndent)))
+		}
+	} else {
+		_, _ = fmt.Fprintln(view, g.theme.paint("valid", gokoban.Indent("Level is valid", editorIndent)))
+	}
+	if len(g.warning) > 0 {
+		_, _ = fmt.Fprintln(view, g.theme.paint("warning", gokoban.Indent(g.warning, editorIndent)))
+	}
+
+	_, _ = fmt.Fprintln(view)
+	_, _ = fmt.Fprint(view, " ")
+	g.printOption("# . $ * @ +", "place", view)
+	g.printOption("SPACE", "floor", view)
+	g.printActions([]string{"narrower", "wider"}, "width", view)
+	g.printActions([]string{"shorter", "taller"}, "height", view)
+	g.printAction("editorNew", "new", view)
+	if e.test != nil {
+		g.printAction("editorTest", "test", view)
+		g.printAction("editorSave", "save", view)
+	}
+	g.printAction("cancel", "back", view)
+	g.printAction("exit", "exit", view)
+}
@@ -5,9 +5,7 @@ import (
 	"github.com/jroimartin/gocui"
 	"github.com/x-cellent/gokoban/command"
 	"github.com/x-cellent/gokoban/gokoban"
-	"io/ioutil"
-	"strconv"
-	"strings"
+	"github.com/x-cellent/gokoban/profile"
 	"time"
 )
 
@@ -16,55 +14,21 @@ const (
 	replaySpeedDecrement = 20
 )
 
-const (
-	black   = "\u001b[30m"
-	red     = "\u001b[31m"
-	green   = "\u001b[32m"
-	yellow  = "\u001b[33m"
-	blue    = "\u001b[34m"
-	magenta = "\u001b[35m"
-	cyan    = "\u001b[36m"
-	white   = "\u001b[37m"
-
-	bgBlack   = "\u001b[40m"
-	bgRed     = "\u001b[41m"
-	bgGreen   = "\u001b[42m"
-	bgYellow  = "\u001b[43m"
-	bgBlue    = "\u001b[44m"
-	bgMagenta = "\u001b[45m"
-	bgCyan    = "\u001b[46m"
-	bgWhite   = "\u001b[47m"
-
-	brightBlack   = "\u001b[90m"
-	brightRed     = "\u001b[91m"
-	brightGreen   = "\u001b[92m"
-	brightYellow  = "\u001b[93m"
-	brightBlue    = "\u001b[94m"
-	brightMagenta = "\u001b[95m"
-	brightCyan    = "\u001b[96m"
-	brightWhite   = "\u001b[97m"
-
-	bgBrightBlack   = "\u001b[100m"
-	bgBrightRed     = "\u001b[101m"
-	bgBrightGreen   = "\u001b[102m"
-	bgBrightYellow  = "\u001b[103m"
-	bgBrightBlue    = "\u001b[104m"
-	bgBrightMagenta = "\u001b[105m"
-	bgBrightCyan    = "\u001b[106m"
-	bgBrightWhite   = "\u001b[107m"
-
-	reset = "\u001b[0m"
-)
+const reset = "\u001b[0m"
 
-const (
-	targetColor = green
-	boxColor    = bgBlue
-	brickColor  = bgYellow
-	playerColor = bgWhite
-)
+const warningDuration = 2 * time.Second
+
+// selection is a cursor on the board. pick is called with the cursor
+// position once it is confirmed and returns false to keep selecting.
+type selection struct {
+	gokoban.Position
+	hint string
+	pick func(p gokoban.Position) bool
+}
 
 type game struct {
-	dir          string
+	path         string
+	collection   *gokoban.Collection
 	lvl          int
 	level        *gokoban.Level
 	gui          *gocui.Gui
@@ -72,47 +36,65 @@ type game struct {
 	replaySpeed  uint
 	replayPaused bool
 	replayIndex  int
+	warning      string
+	rotation     int
+	forward      *gokoban.Level
+	pulling      bool
+	cursor       *selection
+	profile      *profile.Profile
+	levels       *levelSelection
+	editor       *editor
+	keys         keyMap
+	theme        *theme
+	board        viewport
+	wide         bool
+	viewWidth    int
+	viewHeight   int
+	hint         *hint
+	hinting      bool
+}
+
+var rotations = []gokoban.Transformation{
+	gokoban.Identity,
+	gokoban.Rotate90,
+	gokoban.Rotate180,
+	gokoban.Rotate270,
 }
 
-func newGame(dir string, level int, gui *gocui.Gui) *game {
+func newGame(path string, collection *gokoban.Collection, level int, gui *gocui.Gui) *game {
 	return &game{
-		dir:  dir,
-		lvl:  level,
-		gui:  gui,
-		view: "game",
+		path:       path,
+		collection: collection,
+		lvl:        level,
+		gui:        gui,
+		view:       "game",
+		keys:       defaultKeyMap(),
+		theme:      defaultTheme(),
 	}
 }
 
 func (g *game) maxLevel() int {
-	files, err := ioutil.ReadDir(g.dir)
-	if err != nil {
-		return 0
-	}
-
-	max := 0
-	for _, file := range files {
-		if !strings.HasPrefix(file.Name(), "level") || !strings.HasSuffix(file.Name(), ".txt") {
-			continue
-		}
-		if lvl, err := strconv.Atoi(file.Name()[5 : len(file.Name())-4]); err == nil && max < lvl {
-			max = lvl
-		}
-	}
-
-	return max
+	return len(g.collection.Levels)
 }
 
+// loadLevel starts the current level as a new attempt.
 func (g *game) loadLevel() {
-	g.level = gokoban.NewLevel(
-		fmt.Sprintf("%s/level%d.txt", g.dir, g.lvl),
-		fmt.Sprintf("%s/solution%d.txt", g.dir, g.lvl),
-	)
+	g.openLevel()
+	g.addAttempt()
+	g.saveSession()
+	g.update()
+}
+
+// openLevel shows the current level in its initial position.
+func (g *game) openLevel() {
+	g.level = g.collection.Levels[g.lvl-1]
+	g.rotation = 0
+	g.forward = nil
 	err := g.layout(g.gui)
 	if err != nil {
 		panic(err)
 	}
 	g.reset()
-	g.update()
 }
 
 func (g *game) replaying() bool {
@@ -134,6 +116,10 @@ func (g *game) arrowUpHandler(gui *gocui.Gui, v *gocui.View) error {
 		}
 		return nil
 	}
+	if g.cursor != nil {
+		g.moveCursor(gokoban.Up)
+		return nil
+	}
 	if g.level.Completed() {
 		return nil
 	}
@@ -150,6 +136,10 @@ func (g *game) arrowRightHandler(gui *gocui.Gui, v *gocui.View) error {
 		}
 		return nil
 	}
+	if g.cursor != nil {
+		g.moveCursor(gokoban.Right)
+		return nil
+	}
 	if g.level.Completed() {
 		return nil
 	}
@@ -166,6 +156,10 @@ func (g *game) arrowDownHandler(gui *gocui.Gui, v *gocui.View) error {
 		}
 		return nil
 	}
+	if g.cursor != nil {
+		g.moveCursor(gokoban.Down)
+		return nil
+	}
 	if g.level.Completed() {
 		return nil
 	}
@@ -182,6 +176,10 @@ func (g *game) arrowLeftHandler(gui *gocui.Gui, v *gocui.View) error {
 		}
 		return nil
 	}
+	if g.cursor != nil {
+		g.moveCursor(gokoban.Left)
+		return nil
+	}
 	if g.level.Completed() {
 		return nil
 	}
@@ -195,8 +193,8 @@ func (g *game) undoHandler(gui *gocui.Gui, v *gocui.View) error {
 	if g.replaying() || g.level.Completed() {
 		return nil
 	}
-	if v != nil {
-		command.Bus.UndoLast()
+	if v != nil && !command.Bus.UndoLast() {
+		g.undoLastMove()
 	}
 	return nil
 }
@@ -206,7 +204,18 @@ func (g *game) redoHandler(gui *gocui.Gui, v *gocui.View) error {
 		return nil
 	}
 	if v != nil {
-		command.Bus.RedoLast()
+		g.redo()
+	}
+	return nil
+}
+
+func (g *game) branchHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() {
+		return nil
+	}
+	if v != nil && len(g.level.Branches()) > 1 {
+		g.level.SelectBranch((g.level.SelectedBranch() + 1) % len(g.level.Branches()))
+		g.update()
 	}
 	return nil
 }
@@ -217,12 +226,13 @@ func (g *game) resetHandler(gui *gocui.Gui, v *gocui.View) error {
 	}
 	if v != nil {
 		g.reset()
+		g.addAttempt()
 	}
 	return nil
 }
 
 func (g *game) nextLevelHandler(gui *gocui.Gui, v *gocui.View) error {
-	if g.replaying() || g.level.Completed() {
+	if g.replaying() || g.level.Completed() || g.playTesting() {
 		return nil
 	}
 	if v != nil {
@@ -237,7 +247,7 @@ func (g *game) previousLevelHandler(gui *gocui.Gui, v *gocui.View) error {
 		g.update()
 		return nil
 	}
-	if g.level.Completed() {
+	if g.level.Completed() || g.playTesting() {
 		return nil
 	}
 	if v != nil {
@@ -253,29 +263,180 @@ func (g *game) replaySolutionHandler(gui *gocui.Gui, v *gocui.View) error {
 		return nil
 	}
 
+	if g.level.Reversed() || g.playTesting() {
+		return nil
+	}
+
 	g.reset()
 	g.replaySpeed = defaultReplaySpeed
+	g.startReplay()
+
+	return nil
+}
 
+// startReplay plays the solution from replayIndex on.
+func (g *game) startReplay() {
 	go func() {
 		for g.replaying() && g.replayIndex < len(g.level.Solution) {
 			if !g.replayPaused {
-				switch g.level.Solution[g.replayIndex] {
-				case gokoban.Up:
-					command.Bus.Do(command.NewMoveUp())
-				case gokoban.Right:
-					command.Bus.Do(command.NewMoveRight())
-				case gokoban.Down:
-					command.Bus.Do(command.NewMoveDown())
-				case gokoban.Left:
-					command.Bus.Do(command.NewMoveLeft())
+				course := g.level.Solution[g.replayIndex]
+				if !g.level.CanMove(course) {
+					g.replaySpeed = 0
+					g.flashWarning(fmt.Sprintf("Solution is invalid at step %d", g.replayIndex+1))
+					g.update()
+					break
 				}
+				command.Bus.Do(newMoveCommand(course))
 				g.replayIndex++
 				g.refresh()
 			}
 			time.Sleep(time.Duration(g.replaySpeed) * time.Millisecond)
 		}
 	}()
+}
+
+func (g *game) rotateHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() {
+		return nil
+	}
+	if v != nil {
+		g.rotate()
+	}
+	return nil
+}
+
+func (g *game) wideHandler(gui *gocui.Gui, v *gocui.View) error {
+	if v != nil {
+		g.wide = !g.wide
+		g.update()
+	}
+	return nil
+}
 
+func (g *game) reverseHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() {
+		return nil
+	}
+	if v != nil {
+		g.toggleReverse()
+	}
+	return nil
+}
+
+func (g *game) pullHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() || !g.level.Reversed() {
+		return nil
+	}
+	if v != nil {
+		g.pulling = !g.pulling
+		g.update()
+	}
+	return nil
+}
+
+func (g *game) placeHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() || !g.level.Reversed() || g.level.MoveCount() > 0 {
+		return nil
+	}
+	if v != nil {
+		if g.cursor != nil {
+			g.cursor = nil
+			g.update()
+			return nil
+		}
+		g.selectField("place player", g.playerPosition(), func(p gokoban.Position) bool {
+			if !g.level.PlacePlayer(p.Col, p.Row) {
+				g.flashWarning("The player cannot be placed there")
+				return false
+			}
+			return true
+		})
+	}
+	return nil
+}
+
+func (g *game) walkHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() {
+		return nil
+	}
+	if v != nil {
+		if g.cursor != nil {
+			g.cursor = nil
+			g.update()
+			return nil
+		}
+		g.selectField("walk", g.playerPosition(), g.walkTo)
+	}
+	return nil
+}
+
+func (g *game) pushBoxHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() || g.level.Reversed() {
+		return nil
+	}
+	if v != nil {
+		if g.cursor != nil {
+			g.cursor = nil
+			g.update()
+			return nil
+		}
+		g.selectField("select box", g.playerPosition(), g.selectBox)
+	}
+	return nil
+}
+
+func (g *game) enterHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.cursor == nil && !g.offersUndo() {
+		return nil
+	}
+	if v != nil {
+		if g.cursor == nil {
+			g.undoToSolvable()
+			return nil
+		}
+		g.pick(g.cursor.Position)
+	}
+	return nil
+}
+
+func (g *game) escapeHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.cursor == nil && !g.offersUndo() && !g.playTesting() {
+		return nil
+	}
+	if v != nil {
+		if g.offersUndo() {
+			g.hint = nil
+			g.update()
+			return nil
+		}
+		if g.cursor == nil {
+			g.editLevel()
+			return nil
+		}
+		g.cursor = nil
+		g.update()
+	}
+	return nil
+}
+
+// clickHandler picks the clicked field if a selection is active and walks
+// there otherwise.
+func (g *game) clickHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() || g.levels != nil || g.editing() {
+		return nil
+	}
+	if v != nil {
+		x, y := v.Cursor()
+		p, ok := g.fieldAt(x, y)
+		if !ok {
+			return nil
+		}
+		if g.cursor != nil {
+			g.pick(p)
+		} else {
+			g.walkTo(p)
+		}
+	}
 	return nil
 }
 
@@ -283,61 +444,181 @@ func (g *game) refreshHandler(gui *gocui.Gui) error {
 	return nil
 }
 
+func (g *game) saveSlotHandler(slot int) func(*gocui.Gui, *gocui.View) error {
+	return func(gui *gocui.Gui, v *gocui.View) error {
+		if v != nil && !g.playTesting() {
+			g.saveSlot(slot)
+		}
+		return nil
+	}
+}
+
+func (g *game) loadSlotHandler(slot int) func(*gocui.Gui, *gocui.View) error {
+	return func(gui *gocui.Gui, v *gocui.View) error {
+		if g.replaying() || g.playTesting() {
+			return nil
+		}
+		if v != nil {
+			g.loadSlot(slot)
+		}
+		return nil
+	}
+}
+
 func (g *game) quitHandler(gui *gocui.Gui, v *gocui.View) error {
+	g.saveSession()
 	return gocui.ErrQuit
 }
 
 func (g *game) keyBindings() error {
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyArrowUp, gocui.ModNone, g.arrowUpHandler); err != nil {
+	if err := g.bind(g.view, "up", g.arrowUpHandler); err != nil {
+		return err
+	}
+	if err := g.bind(g.view, "right", g.arrowRightHandler); err != nil {
+		return err
+	}
+	if err := g.bind(g.view, "down", g.arrowDownHandler); err != nil {
+		return err
+	}
+	if err := g.bind(g.view, "left", g.arrowLeftHandler); err != nil {
+		return err
+	}
+	if err := g.bind(g.view, "undo", g.undoHandler); err != nil {
+		return err
+	}
+	if err := g.bind(g.view, "redo", g.redoHandler); err != nil {
+		return err
+	}
+	if err := g.bind(g.view, "branch", g.branchHandler); err != nil {
+		return err
+	}
+	if err := g.bind(g.view, "reset", g.resetHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyArrowRight, gocui.ModNone, g.arrowRightHandler); err != nil {
+	if err := g.bind(g.view, "next", g.nextLevelHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyArrowDown, gocui.ModNone, g.arrowDownHandler); err != nil {
+	if err := g.bind(g.view, "previous", g.previousLevelHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyArrowLeft, gocui.ModNone, g.arrowLeftHandler); err != nil {
+	if err := g.bind(g.view, "solution", g.replaySolutionHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyCtrlZ, gocui.ModNone, g.undoHandler); err != nil {
+	if err := g.bind(g.view, "rotate", g.rotateHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyCtrlY, gocui.ModNone, g.redoHandler); err != nil {
+	if err := g.bind(g.view, "wide", g.wideHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyCtrlU, gocui.ModNone, g.undoHandler); err != nil {
+	if err := g.bind(g.view, "hint", g.hintHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyCtrlR, gocui.ModNone, g.redoHandler); err != nil {
+	if err := g.bind(g.view, "reverse", g.reverseHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyCtrlSpace, gocui.ModNone, g.resetHandler); err != nil {
+	if err := g.bind(g.view, "pull", g.pullHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyCtrlN, gocui.ModNone, g.nextLevelHandler); err != nil {
+	if err := g.bind(g.view, "place", g.placeHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyCtrlP, gocui.ModNone, g.previousLevelHandler); err != nil {
+	if err := g.bind(g.view, "walk", g.walkHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding(g.view, gocui.KeyCtrlS, gocui.ModNone, g.replaySolutionHandler); err != nil {
+	if err := g.bind(g.view, "pushBox", g.pushBoxHandler); err != nil {
 		return err
 	}
-	if err := g.gui.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, g.quitHandler); err != nil {
+	if err := g.bind(g.view, "confirm", g.enterHandler); err != nil {
 		return err
 	}
+	if err := g.bind(g.view, "cancel", g.escapeHandler); err != nil {
+		return err
+	}
+	if err := g.gui.SetKeybinding(g.view, gocui.MouseLeft, gocui.ModNone, g.clickHandler); err != nil {
+		return err
+	}
+	for i, k := range g.keys["save"] {
+		if err := g.gui.SetKeybinding(g.view, k.value, gocui.ModNone, g.saveSlotHandler(i+1)); err != nil {
+			return err
+		}
+	}
+	for i, k := range g.keys["load"] {
+		if err := g.gui.SetKeybinding(g.view, k.value, gocui.ModNone, g.loadSlotHandler(i+1)); err != nil {
+			return err
+		}
+	}
+	if err := g.bind(g.view, "levels", g.levelSelectionHandler); err != nil {
+		return err
+	}
+	if err := g.levelSelectionKeyBindings(); err != nil {
+		return err
+	}
+	if err := g.bind(g.view, "edit", g.editHandler); err != nil {
+		return err
+	}
+	if err := g.editorKeyBindings(); err != nil {
+		return err
+	}
+	// Characters quit the game only from the game view, so that they can
+	// still be typed into the editor.
+	for _, k := range g.keys["exit"] {
+		view := ""
+		if _, ok := k.value.(rune); ok {
+			view = g.view
+		}
+		if err := g.gui.SetKeybinding(view, k.value, gocui.ModNone, g.quitHandler); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
 func (g *game) movePlayer(course gokoban.Course) {
-	g.level.Move(course)
+	g.move(func() {
+		if g.level.Reversed() && g.pulling && g.level.CanPull(course) {
+			g.level.Pull(course)
+		} else {
+			g.level.Move(course)
+		}
+	})
+}
+
+// followPath moves along path. Reverse games never pull on the way.
+func (g *game) followPath(path []gokoban.Course) {
+	g.move(func() {
+		for _, course := range path {
+			g.level.Move(course)
+		}
+	})
+}
+
+// move runs f and reacts to new deadlocks and the completed level.
+func (g *game) move(f func()) {
+	deadlocked := g.level.Deadlocked()
+	f()
+
+	if !deadlocked && g.level.Deadlocked() {
+		g.flashWarning("Deadlock! Undo with ^z")
+	}
+
+	if g.level.Reversed() && g.level.Completed() {
+		g.applyReverseSolution()
+	}
+
+	if g.level.Completed() && g.playTesting() {
+		go func() {
+			time.Sleep(2 * time.Second)
+			g.editLevel()
+			g.refresh()
+		}()
+		return
+	}
 
 	if g.level.Completed() {
 		go func() {
 			if g.replaySpeed == 0 {
-				g.level.PrintSolution(fmt.Sprintf("my-solution%d.txt", g.lvl))
+				g.addSolution(g.level.Transform(rotations[(len(rotations)-g.rotation)%len(rotations)]))
 			}
 			time.Sleep(2 * time.Second)
 			g.nextLevel()
@@ -346,6 +627,51 @@ func (g *game) movePlayer(course gokoban.Course) {
 	}
 }
 
+func (g *game) addAttempt() {
+	if g.profile == nil || g.level.Reversed() || g.editor != nil {
+		return
+	}
+	if r, err := g.profile.AddAttempt(g.level); err == nil {
+		g.showPersonalBest(r)
+	}
+}
+
+// addSolution records level, which must not be rotated.
+func (g *game) addSolution(level *gokoban.Level) {
+	if g.profile == nil || g.editor != nil {
+		return
+	}
+	r, improved, err := g.profile.AddSolution(level, time.Now())
+	if err != nil {
+		g.flashWarning(fmt.Sprintf("Solution not recorded: %v", err))
+		return
+	}
+	g.showPersonalBest(r)
+	if improved {
+		g.flashWarning("New personal best!")
+	}
+	g.update()
+}
+
+func (g *game) showPersonalBest(r *profile.Record) {
+	if r.Solved() {
+		g.level.SetPersonalBest(r.BestMoves.Moves, r.BestPushes.Pushes)
+	}
+}
+
+func (g *game) flashWarning(warning string) {
+	g.warning = warning
+	time.AfterFunc(warningDuration, func() {
+		g.gui.Update(func(gui *gocui.Gui) error {
+			if g.warning == warning {
+				g.warning = ""
+				g.update()
+			}
+			return nil
+		})
+	})
+}
+
 func (g *game) hasPreviousLevel() bool {
 	return g.lvl > 1
 }
@@ -370,6 +696,119 @@ func (g *game) previousLevel() {
 	}
 }
 
+// toggleReverse switches between the level and its reverse game. Both
+// start over since their moves cannot be mixed.
+func (g *game) toggleReverse() {
+	if g.forward != nil {
+		g.level, g.forward = g.forward, nil
+	} else {
+		g.forward, g.level = g.level, g.level.Reverse()
+	}
+	g.pulling = true
+	g.reset()
+}
+
+// applyReverseSolution plays the solution found by a completed reverse
+// game on the forward level.
+func (g *game) applyReverseSolution() {
+	solution, err := g.level.ForwardSolution()
+	if err != nil {
+		return
+	}
+	g.level, g.forward = g.forward, nil
+	g.level.Reset()
+	for _, course := range solution {
+		g.level.Move(course)
+	}
+}
+
+func (g *game) walkTo(p gokoban.Position) bool {
+	path, ok := g.level.PathTo(p.Col, p.Row)
+	if !ok {
+		g.flashWarning("There is no way to get there")
+		g.update()
+		return false
+	}
+	if len(path) > 0 {
+		command.Bus.Do(command.NewFollowPath(path))
+	}
+	return true
+}
+
+// selectBox continues with the destination of the box at p.
+func (g *game) selectBox(p gokoban.Position) bool {
+	if !g.level.IsBox(p.Col, p.Row) {
+		g.flashWarning("Please select a box")
+		return false
+	}
+	g.selectField("push box here", p, func(to gokoban.Position) bool {
+		path, ok := g.level.PlanBoxPush(p, to)
+		if !ok {
+			g.flashWarning("The box cannot be pushed there")
+			return false
+		}
+		if len(path) > 0 {
+			command.Bus.Do(command.NewFollowPath(path))
+		}
+		return true
+	})
+	return true
+}
+
+func (g *game) selectField(hint string, start gokoban.Position, pick func(p gokoban.Position) bool) {
+	g.cursor = &selection{
+		Position: start,
+		hint:     hint,
+		pick:     pick,
+	}
+	g.update()
+}
+
+func (g *game) playerPosition() gokoban.Position {
+	c, r := g.level.PlayerPosition()
+	return gokoban.Position{Col: c, Row: r}
+}
+
+func (g *game) pick(p gokoban.Position) {
+	s := g.cursor
+	if s.pick(p) && g.cursor == s {
+		g.cursor = nil
+	}
+	g.update()
+}
+
+func (g *game) moveCursor(course gokoban.Course) {
+	p := g.cursor.Neighbor(course)
+	if p.Col >= 0 && p.Row >= 0 && p.Col < g.level.Width() && p.Row < g.level.Height() {
+		g.cursor.Position = p
+		g.update()
+	}
+}
+
+// rotate turns the board clockwise. The bus history is cleared because
+// its commands refer to the courses of the previous orientation.
+func (g *game) rotate() {
+	g.level = g.level.Transform(gokoban.Rotate90)
+	if g.forward != nil {
+		g.forward = g.forward.Transform(gokoban.Rotate90)
+	}
+	g.rotation = (g.rotation + 1) % len(rotations)
+	command.Bus.Clear()
+	if err := g.layout(g.gui); err != nil {
+		panic(err)
+	}
+	g.update()
+}
+
+// redo repeats the next move of the level history through the bus, so
+// that it can be undone like any other move. The level replays a pull as a
+// pull, whatever mode the player is in.
+func (g *game) redo() {
+	if g.level.CanRedo() {
+		command.Bus.Do(command.NewRedo())
+	}
+}
+
 func (g *game) undoLastMove() {
 	if g.level.MoveCount() > 0 {
 		g.level.UndoLastMove()
@@ -381,14 +820,17 @@ func (g *game) reset() {
 	g.replaySpeed = 0
 	g.replayPaused = false
 	g.replayIndex = 0
+	g.warning = ""
+	g.cursor = nil
+	g.hint = nil
 	g.level.Reset()
 	command.Bus.Clear()
 	g.update()
 }
 
 func (g *game) update() {
-	v := g.gui.CurrentView()
-	if v == nil {
+	v, err := g.gui.View(g.view)
+	if err != nil {
 		return
 	}
 	v.Clear()
@@ -397,86 +839,186 @@ func (g *game) update() {
 }
 
 func (g *game) refresh() {
-	g.gui.Execute(g.refreshHandler)
+	g.gui.Update(g.refreshHandler)
 }
 
 func (g *game) printOption(option, description string, view *gocui.View) {
-	_, _ = fmt.Fprintf(view, "%s%s%s%s", bgWhite, black, option, reset)
+	_, _ = fmt.Fprint(view, g.theme.paint("option", option))
 	_, _ = fmt.Fprintf(view, fmt.Sprintf(" %s ", description))
 }
 
+func (g *game) printBoard(view *gocui.View) {
+	deadlocked := make(map[gokoban.Position]bool)
+	for _, deadlock := range g.level.Deadlocks() {
+		for _, p := range deadlock.Boxes {
+			deadlocked[p] = true
+		}
+	}
+
+	hinted := g.hint.current(g.level) && !g.hint.unsolvable
+	g.updateViewport(view)
+	b := g.board
+	for r := b.camera.Row; r < b.camera.Row+b.rows; r++ {
+		_, _ = fmt.Fprint(view, gokoban.Indent("", b.x))
+		for c := b.camera.Col; c < b.camera.Col+b.cols; c++ {
+			name := fieldStyle(g.level.Symbol(c, r))
+			var overlays []string
+			if name == "free" && g.level.IsDeadSquare(c, r) {
+				name = "deadSquare"
+			}
+			if deadlocked[gokoban.Position{Col: c, Row: r}] {
+				overlays = append(overlays, "deadlock")
+			}
+			if hinted && g.hint.box.Col == c && g.hint.box.Row == r {
+				overlays = append(overlays, "hint")
+			}
+			if g.cursor != nil && g.cursor.Col == c && g.cursor.Row == r {
+				overlays = append(overlays, "cursor")
+			}
+			_, _ = fmt.Fprint(view, g.theme.cell(name, b.cellWidth, overlays...))
+		}
+		_, _ = fmt.Fprintln(view)
+	}
+}
+
 func (g *game) print(view *gocui.View) {
-	level := gokoban.Indent(g.level.String(), 40)
 	levelInfo := fmt.Sprintf("Level %d/%d", g.lvl, g.maxLevel())
+	if len(g.level.Title) > 0 {
+		levelInfo = fmt.Sprintf("%s: %s", levelInfo, g.level.Title)
+	}
+	if g.playTesting() {
+		levelInfo = "Play-test"
+	}
+	if g.level.Reversed() {
+		levelInfo += " (reverse)"
+	}
 	vw, _ := view.Size()
-	s := fmt.Sprintf("%s\n\n%s", gokoban.Indent(levelInfo, (vw-len(levelInfo))/2), level)
-	for i := range s {
-		symbol := string(s[i])
-		if symbol == gokoban.BrickSymbol {
-			_, _ = fmt.Fprintf(view, "%s %s", brickColor, reset)
-		} else if symbol == gokoban.TargetSymbol {
-			_, _ = fmt.Fprintf(view, "%sO%s", targetColor, reset)
-		} else if symbol == gokoban.BoxSymbol {
-			_, _ = fmt.Fprintf(view, "%s %s", boxColor, reset)
-		} else if symbol == gokoban.PlayerSymbol {
-			_, _ = fmt.Fprintf(view, "%s %s", playerColor, reset)
-		} else {
-			_, _ = fmt.Fprint(view, symbol)
-		}
+	_, _ = fmt.Fprintf(view, "%s\n\n", gokoban.Indent(levelInfo, (vw-len(levelInfo))/2))
+	g.printBoard(view)
+	_, _ = fmt.Fprintf(view, "\n%s\n", center(g.level.HUD(), vw))
+	if len(g.warning) > 0 {
+		_, _ = fmt.Fprint(view, g.theme.paint("warning", center(g.warning, vw)))
 	}
 	_, _ = fmt.Fprintln(view)
 	_, _ = fmt.Fprintln(view)
 	_, _ = fmt.Fprint(view, " ")
 	if g.replaying() {
-		g.printOption("^SPACE", "reset", view)
+		g.printAction("reset", "reset", view)
 		if g.canSpeedUpReplay() {
-			g.printOption("right", "faster", view)
+			g.printAction("right", "faster", view)
 		}
 		if g.canSlowDownReplay() {
-			g.printOption("left", "slower", view)
+			g.printAction("left", "slower", view)
 		}
 		if g.canSpeedUpReplay() {
-			g.printOption("up", "faster", view)
+			g.printAction("up", "faster", view)
 		}
 		if g.canSlowDownReplay() {
-			g.printOption("down", "slower", view)
+			g.printAction("down", "slower", view)
 		}
 		p := "pause"
 		if g.replayPaused {
 			p = "continue"
 		}
-		g.printOption("^p", p, view)
-		g.printOption("^s", "stop", view)
-		g.printOption("^c", "exit", view)
+		g.printAction("previous", p, view)
+		g.printAction("solution", "stop", view)
+		g.printAction("exit", "exit", view)
 
 		return
 	}
-	g.printOption("^SPACE", "reset", view)
-	if g.hasPreviousLevel() {
-		g.printOption("^p", "previous", view)
+	if g.cursor != nil {
+		g.printAction("confirm", g.cursor.hint, view)
+		g.printAction("cancel", "cancel", view)
+		g.printAction("exit", "exit", view)
+
+		return
 	}
-	if g.hasNextLevel() {
-		g.printOption("^n", "next", view)
+	if g.offersUndo() {
+		g.printAction("confirm", fmt.Sprintf("undo to move %d", g.hint.undoTo), view)
+		g.printAction("cancel", "keep playing", view)
+		g.printAction("exit", "exit", view)
+
+		return
 	}
-	g.printOption("^u", "undo", view)
-	g.printOption("^r", "redo", view)
-	g.printOption("^z", "undo", view)
-	g.printOption("^y", "redo", view)
-	g.printOption("^s", "solution", view)
-	g.printOption("^c", "exit", view)
+	g.printAction("reset", "reset", view)
+	if g.playTesting() {
+		g.printAction("cancel", "editor", view)
+	} else {
+		if g.hasPreviousLevel() {
+			g.printAction("previous", "previous", view)
+		}
+		if g.hasNextLevel() {
+			g.printAction("next", "next", view)
+		}
+		g.printAction("levels", "levels", view)
+		g.printAction("edit", "edit", view)
+	}
+	g.printAction("undo", "undo", view)
+	g.printAction("redo", "redo", view)
+	if !g.level.Reversed() {
+		g.printAction("hint", "hint", view)
+	}
+	if branches := g.level.Branches(); len(branches) > 1 {
+		g.printAction("branch", fmt.Sprintf("branch %d/%d", g.level.SelectedBranch()+1, len(branches)), view)
+	}
+	if g.level.Reversed() {
+		g.printAction("reverse", "forward", view)
+		if g.pulling {
+			g.printAction("pull", "walk", view)
+		} else {
+			g.printAction("pull", "pull", view)
+		}
+		if g.level.MoveCount() == 0 {
+			g.printAction("place", "place", view)
+		}
+	} else {
+		if !g.playTesting() {
+			g.printAction("solution", "solution", view)
+		}
+		g.printAction("reverse", "reverse", view)
+		g.printAction("pushBox", "push box", view)
+	}
+	g.printAction("walk", "walk to", view)
+	g.printAction("rotate", "rotate", view)
+	if g.wide {
+		g.printAction("wide", "narrow", view)
+	} else {
+		g.printAction("wide", "wide", view)
+	}
+	if !g.playTesting() {
+		g.printAction("save", "save", view)
+		g.printAction("load", "load", view)
+	}
+	g.printAction("exit", "exit", view)
 }
 
+// layout gives the game view the whole terminal. The game is printed again
+// whenever the terminal was resized, so that the board is fitted to the new
+// size.
 func (g *game) layout(gui *gocui.Gui) error {
-	w := g.level.Width() + 80
-	h := g.level.Height() + 3
 	maxX, maxY := gui.Size()
-	if _, err := gui.SetView(g.view, (maxX-w)/2, (maxY-h)/2-6, (maxX+w)/2, (maxY+h)/2); err != nil {
+	if maxX < 4 || maxY < 4 {
+		return nil
+	}
+	v, err := gui.SetView(g.view, 0, 0, maxX-1, maxY-1)
+	if err != nil {
 		if err != gocui.ErrUnknownView {
 			return err
 		}
-		if err := gui.SetCurrentView(g.view); err != nil {
+		if _, err := gui.SetCurrentView(g.view); err != nil {
 			return err
 		}
 	}
+	if maxX != g.viewWidth || maxY != g.viewHeight {
+		g.viewWidth, g.viewHeight = maxX, maxY
+		v.Clear()
+		g.print(v)
+	}
+	if g.levels != nil {
+		return g.layoutLevelSelection(gui)
+	}
+	if g.editing() {
+		return g.layoutEditor(gui)
+	}
 	return nil
 }
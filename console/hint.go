@@ -0,0 +1,217 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/x-cellent/gokoban/command"
+	"github.com/x-cellent/gokoban/gokoban"
+	"github.com/x-cellent/gokoban/solver"
+	"time"
+	"unicode"
+)
+
+// hintTimeout bounds every solver run of a hint, the one for the current
+// position as well as each one looking for the last solvable position.
+const hintTimeout = 10 * time.Second
+
+const searchingHint = "Searching a hint..."
+
+var courseNames = map[gokoban.Course]string{
+	gokoban.Up:    "up",
+	gokoban.Right: "right",
+	gokoban.Down:  "down",
+	gokoban.Left:  "left",
+}
+
+// hint is the solver's advice for the position reached by moves on level.
+// Either box is pushed in course next, or the position is unsolvable and
+// undoTo is the move count of the last solvable position.
+type hint struct {
+	level      *gokoban.Level
+	moves      string
+	box        gokoban.Position
+	course     gokoban.Course
+	unsolvable bool
+	undoTo     int
+}
+
+// current tells whether the hint still applies to the position of l.
+func (h *hint) current(l *gokoban.Level) bool {
+	return h != nil && h.level == l && h.moves == l.Moves()
+}
+
+func (g *game) hintHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() || g.hinting {
+		return nil
+	}
+	if v != nil {
+		if g.level.Reversed() {
+			g.flashWarning("Hints are only given in forward games")
+			g.update()
+			return nil
+		}
+		g.searchHint()
+	}
+	return nil
+}
+
+// offersUndo tells whether the current position is known to be unsolvable.
+func (g *game) offersUndo() bool {
+	return g.hint.current(g.level) && g.hint.unsolvable
+}
+
+// undoToSolvable takes back all moves since the last solvable position.
+// They stay in the level history and can be redone.
+func (g *game) undoToSolvable() {
+	for g.level.MoveCount() > g.hint.undoTo {
+		g.level.UndoLastMove()
+	}
+	g.hint = nil
+	command.Bus.Clear()
+	g.update()
+}
+
+// searchHint runs the solver in the background on a copy of the level and
+// shows its result once the player hasn't moved in the meantime.
+func (g *game) searchHint() {
+	level := g.level
+	h := &hint{
+		level: level,
+		moves: level.Moves(),
+	}
+	g.hint = nil
+
+	clone := level.Clone()
+	g.hinting = true
+	g.warning = searchingHint
+	g.update()
+
+	go func() {
+		err := h.search(clone)
+
+		g.gui.Update(func(gui *gocui.Gui) error {
+			g.hinting = false
+			if g.warning == searchingHint {
+				g.warning = ""
+			}
+			if !h.current(g.level) {
+				g.update()
+				return nil
+			}
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				g.flashWarning(fmt.Sprintf("The solver timed out after %v, no hint found", hintTimeout))
+			case err != nil:
+				g.flashWarning(fmt.Sprintf("No hint: %v", err))
+			case h.unsolvable:
+				g.hint = h
+				g.flashWarning("This position cannot be solved any more")
+			default:
+				g.hint = h
+				g.flashWarning(fmt.Sprintf("Push the marked box %s", courseNames[h.course]))
+			}
+			g.update()
+			return nil
+		})
+	}()
+}
+
+// solve runs the solver on l within hintTimeout. Hints only need some
+// solution, so it doesn't wait for an optimal one.
+func solve(l *gokoban.Level) ([]gokoban.Course, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hintTimeout)
+	defer cancel()
+	return solver.Solve(ctx, l, solver.Fast)
+}
+
+// search finds the next push of a solution from the position of l, or the
+// last solvable position if there is none. l is changed on the way.
+func (h *hint) search(l *gokoban.Level) error {
+	if l.Deadlocked() {
+		// Every position after the deadlocking push is unsolvable as well,
+		// so only the ones before it are searched.
+		h.unsolvable = true
+		lastUndeadlocked(l)
+		if solvable(l) {
+			h.undoTo = l.MoveCount()
+		} else {
+			h.undoTo = lastSolvable(l)
+		}
+		return nil
+	}
+
+	solution, err := solve(l)
+	if err == solver.ErrUnsolvable {
+		h.unsolvable = true
+		h.undoTo = lastSolvable(l)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, course := range solution {
+		c, r := l.PlayerPosition()
+		box := gokoban.Position{Col: c, Row: r}.Neighbor(course)
+		if l.IsBox(box.Col, box.Row) {
+			h.box, h.course = box, course
+			return nil
+		}
+		l.Move(course)
+	}
+	return fmt.Errorf("solution without pushes")
+}
+
+// lastUndeadlocked takes l back to right before the push that deadlocked
+// it and returns that move count.
+func lastUndeadlocked(l *gokoban.Level) int {
+	for l.MoveCount() > 0 && l.Deadlocked() {
+		l.UndoLastMove()
+	}
+	return l.MoveCount()
+}
+
+// lastSolvable returns the largest move count of l whose position is known
+// to be solvable, l itself being unsolvable. Only pushes change that, and every position after an
+// unsolvable one is unsolvable as well, so the pushes are bisected.
+// Positions the solver can't decide in time count as unsolvable, so the
+// result is always safe to go back to.
+func lastSolvable(l *gokoban.Level) int {
+	var pushes []int
+	for i, m := range l.Moves() {
+		if unicode.IsUpper(m) {
+			pushes = append(pushes, i)
+		}
+	}
+	if len(pushes) == 0 {
+		return 0
+	}
+
+	// The position right before pushes[lo] is solvable, the one right
+	// before pushes[hi] is not. pushes[len(pushes)] would be the current
+	// position.
+	lo, hi := 0, len(pushes)
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		before := l.Clone()
+		for before.MoveCount() > pushes[mid] {
+			before.UndoLastMove()
+		}
+		if solvable(before) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return pushes[lo]
+}
+
+// solvable tells whether the solver finds a solution for l in time.
+func solvable(l *gokoban.Level) bool {
+	if l.Deadlocked() {
+		return false
+	}
+	_, err := solve(l)
+	return err == nil
+}
@@ -0,0 +1,94 @@
+package console
+
+import (
+	"github.com/x-cellent/gokoban/gokoban"
+	"strings"
+	"testing"
+)
+
+func TestLastSolvable(t *testing.T) {
+	room := "#######\n#     #\n# @$ .#\n#     #\n#######"
+	tests := []struct {
+		name           string
+		moves          string
+		wantUndeadlock int
+		wantSolvable   int
+	}{
+		{name: "no pushes", moves: "ud", wantUndeadlock: 2, wantSolvable: 0},
+		{name: "pushed onto dead square", moves: "drU", wantUndeadlock: 2, wantSolvable: 2},
+		{name: "walked after the deadlock", moves: "drUll", wantUndeadlock: 2, wantSolvable: 2},
+		{name: "deadlocked by the second push", moves: "RdrU", wantUndeadlock: 3, wantSolvable: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := gokoban.LoadLevel(strings.NewReader(room))
+			if err != nil {
+				t.Fatal(err)
+			}
+			courses, err := gokoban.ParseLURD(tt.moves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range courses {
+				if !l.CanMove(c) {
+					t.Fatalf("cannot move %v:\n%v", c, l)
+				}
+				l.Move(c)
+			}
+			if got := lastUndeadlocked(l.Clone()); got != tt.wantUndeadlock {
+				t.Errorf("lastUndeadlocked() = %d, want %d", got, tt.wantUndeadlock)
+			}
+			if got := lastSolvable(l); got != tt.wantSolvable {
+				t.Errorf("lastSolvable() = %d, want %d", got, tt.wantSolvable)
+			}
+			if got := l.MoveCount(); got != len(courses) {
+				t.Errorf("lastSolvable() changed the level to %d moves", got)
+			}
+		})
+	}
+}
+
+func TestSearchDeadlocked(t *testing.T) {
+	room := "#######\n#     #\n# @$ .#\n#     #\n#######"
+	// The alcove holds one box only, but a second one pushed in isn't
+	// deadlocked before it is pushed onto the first.
+	alcove := "########\n#@     #\n# $$  .#\n## #####\n## #####\n##.#####\n########"
+	tests := []struct {
+		name   string
+		input  string
+		moves  string
+		undoTo int
+	}{
+		{name: "solvable before the deadlock", input: room, moves: "drUll", undoTo: 2},
+		{name: "unsolvable before the deadlock", input: alcove, moves: "rDDDuuurrdLulDD", undoTo: 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := gokoban.LoadLevel(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			courses, err := gokoban.ParseLURD(tt.moves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range courses {
+				if !l.CanMove(c) {
+					t.Fatalf("cannot move %v:\n%v", c, l)
+				}
+				l.Move(c)
+			}
+			if !l.Deadlocked() {
+				t.Fatalf("level is not deadlocked:\n%v", l)
+			}
+
+			h := &hint{}
+			if err := h.search(l.Clone()); err != nil {
+				t.Fatal(err)
+			}
+			if !h.unsolvable || h.undoTo != tt.undoTo {
+				t.Errorf("search() = unsolvable %v, undo to %d, want true, %d", h.unsolvable, h.undoTo, tt.undoTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/x-cellent/gokoban/gokoban"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// keysFile overrides the default key bindings, for example
+//
+//	{
+//	  "up": ["up", "k", "w"],
+//	  "undo": ["ctrl-z", "u"],
+//	  "reset": ["ctrl-q"]
+//	}
+//
+// Every action listed replaces its default keys, an empty list unbinds it.
+// Actions of different views, like next and editorNew, may share keys.
+const keysFile = "keys.json"
+
+// defaultKeys are the keys of each action. The keys of save and load
+// select the slots in order.
+var defaultKeys = map[string][]string{
+	"up":         {"up"},
+	"right":      {"right"},
+	"down":       {"down"},
+	"left":       {"left"},
+	"undo":       {"ctrl-z", "ctrl-u"},
+	"redo":       {"ctrl-y", "ctrl-r"},
+	"branch":     {"ctrl-v"},
+	"reset":      {"ctrl-space"},
+	"next":       {"ctrl-n"},
+	"previous":   {"ctrl-p"},
+	"solution":   {"ctrl-s"},
+	"rotate":     {"ctrl-t"},
+	"reverse":    {"ctrl-b"},
+	"pull":       {"ctrl-g"},
+	"place":      {"ctrl-o"},
+	"walk":       {"ctrl-w"},
+	"pushBox":    {"ctrl-e"},
+	"confirm":    {"enter"},
+	"cancel":     {"esc"},
+	"levels":     {"ctrl-l"},
+	"edit":       {"ctrl-d"},
+	"wide":       {"ctrl-x"},
+	"hint":       {"ctrl-a"},
+	"save":       {"f1", "f2", "f3", "f4"},
+	"load":       {"f5", "f6", "f7", "f8"},
+	"pageUp":     {"pgup"},
+	"pageDown":   {"pgdn"},
+	"first":      {"home"},
+	"last":       {"end"},
+	"editorNew":  {"ctrl-n"},
+	"editorTest": {"ctrl-t"},
+	"editorSave": {"ctrl-s"},
+	"narrower":   {"["},
+	"wider":      {"]"},
+	"shorter":    {"{"},
+	"taller":     {"}"},
+	"exit":       {"ctrl-c"},
+}
+
+// keyContexts lists the actions bound in each view. A key must trigger only
+// one action per view, but may do different things in different views.
+var keyContexts = map[string][]string{
+	"game": {
+		"up", "right", "down", "left", "undo", "redo", "branch", "reset",
+		"next", "previous", "solution", "rotate", "reverse", "pull", "place",
+		"walk", "pushBox", "confirm", "cancel", "levels", "edit", "wide",
+		"hint", "save", "load", "exit",
+	},
+	levelSelectionView: {
+		"up", "down", "pageUp", "pageDown", "first", "last", "confirm",
+		"cancel", "levels", "exit",
+	},
+	editorView: {
+		"up", "right", "down", "left", "editorNew", "editorTest",
+		"editorSave", "narrower", "wider", "shorter", "taller", "cancel",
+		"edit", "exit",
+	},
+}
+
+// editorSymbolKeys place fields in the editor and cannot be rebound.
+var editorSymbolKeys = []interface{}{
+	rune(gokoban.BrickSymbol[0]),
+	rune(gokoban.TargetSymbol[0]),
+	rune(gokoban.BoxSymbol[0]),
+	rune(gokoban.BoxOnTargetSymbol[0]),
+	rune(gokoban.PlayerSymbol[0]),
+	rune(gokoban.PlayerOnTargetSymbol[0]),
+	gocui.KeySpace,
+	gocui.KeyDelete,
+	gocui.KeyBackspace,
+	gocui.KeyBackspace2,
+}
+
+var namedKeys = map[string]gocui.Key{
+	"up":         gocui.KeyArrowUp,
+	"right":      gocui.KeyArrowRight,
+	"down":       gocui.KeyArrowDown,
+	"left":       gocui.KeyArrowLeft,
+	"enter":      gocui.KeyEnter,
+	"esc":        gocui.KeyEsc,
+	"space":      gocui.KeySpace,
+	"tab":        gocui.KeyTab,
+	"backspace":  gocui.KeyBackspace2,
+	"insert":     gocui.KeyInsert,
+	"delete":     gocui.KeyDelete,
+	"home":       gocui.KeyHome,
+	"end":        gocui.KeyEnd,
+	"pgup":       gocui.KeyPgup,
+	"pgdn":       gocui.KeyPgdn,
+	"f1":         gocui.KeyF1,
+	"f2":         gocui.KeyF2,
+	"f3":         gocui.KeyF3,
+	"f4":         gocui.KeyF4,
+	"f5":         gocui.KeyF5,
+	"f6":         gocui.KeyF6,
+	"f7":         gocui.KeyF7,
+	"f8":         gocui.KeyF8,
+	"f9":         gocui.KeyF9,
+	"f10":        gocui.KeyF10,
+	"f11":        gocui.KeyF11,
+	"f12":        gocui.KeyF12,
+	"ctrl-space": gocui.KeyCtrlSpace,
+	"ctrl-a":     gocui.KeyCtrlA,
+	"ctrl-b":     gocui.KeyCtrlB,
+	"ctrl-c":     gocui.KeyCtrlC,
+	"ctrl-d":     gocui.KeyCtrlD,
+	"ctrl-e":     gocui.KeyCtrlE,
+	"ctrl-f":     gocui.KeyCtrlF,
+	"ctrl-g":     gocui.KeyCtrlG,
+	"ctrl-h":     gocui.KeyCtrlH,
+	"ctrl-i":     gocui.KeyCtrlI,
+	"ctrl-j":     gocui.KeyCtrlJ,
+	"ctrl-k":     gocui.KeyCtrlK,
+	"ctrl-l":     gocui.KeyCtrlL,
+	"ctrl-m":     gocui.KeyCtrlM,
+	"ctrl-n":     gocui.KeyCtrlN,
+	"ctrl-o":     gocui.KeyCtrlO,
+	"ctrl-p":     gocui.KeyCtrlP,
+	"ctrl-q":     gocui.KeyCtrlQ,
+	"ctrl-r":     gocui.KeyCtrlR,
+	"ctrl-s":     gocui.KeyCtrlS,
+	"ctrl-t":     gocui.KeyCtrlT,
+	"ctrl-u":     gocui.KeyCtrlU,
+	"ctrl-v":     gocui.KeyCtrlV,
+	"ctrl-w":     gocui.KeyCtrlW,
+	"ctrl-x":     gocui.KeyCtrlX,
+	"ctrl-y":     gocui.KeyCtrlY,
+	"ctrl-z":     gocui.KeyCtrlZ,
+}
+
+// key is either a gocui.Key or a rune, as accepted by SetKeybinding.
+type key struct {
+	value interface{}
+	label string
+}
+
+// parseKey accepts the names of namedKeys in any case and single
+// characters.
+func parseKey(name string) (key, error) {
+	lower := strings.ToLower(name)
+	if k, ok := namedKeys[lower]; ok {
+		label := lower
+		switch {
+		case lower == "ctrl-space":
+			label = "^SPACE"
+		case strings.HasPrefix(lower, "ctrl-"):
+			label = "^" + lower[5:]
+		case lower[0] == 'f' && len(lower) > 1:
+			label = strings.ToUpper(lower)
+		}
+		return key{value: k, label: label}, nil
+	}
+	if r := []rune(name); len(r) == 1 {
+		return key{value: r[0], label: name}, nil
+	}
+	return key{}, fmt.Errorf("unknown key %q", name)
+}
+
+// keyMap holds the keys of every action.
+type keyMap map[string][]key
+
+func defaultKeyMap() keyMap {
+	km := make(keyMap)
+	for action, names := range defaultKeys {
+		for _, name := range names {
+			k, err := parseKey(name)
+			if err != nil {
+				panic(err)
+			}
+			km[action] = append(km[action], k)
+		}
+	}
+	return km
+}
+
+// loadKeyMap returns the default key map changed by the named config file,
+// which may be missing.
+func loadKeyMap(name string) (keyMap, error) {
+	km := defaultKeyMap()
+	path, err := configPath(name)
+	if err != nil {
+		return nil, err
+	}
+	bb, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return km, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config map[string][]string
+	if err := json.Unmarshal(bb, &config); err != nil {
+		return nil, fmt.Errorf("key bindings %q are not valid: %w", path, err)
+	}
+	for action, names := range config {
+		if _, ok := defaultKeys[action]; !ok {
+			return nil, fmt.Errorf("unknown action %q", action)
+		}
+		km[action] = nil
+		for _, name := range names {
+			k, err := parseKey(name)
+			if err != nil {
+				return nil, fmt.Errorf("action %q: %w", action, err)
+			}
+			km[action] = append(km[action], k)
+		}
+	}
+	if len(km["save"]) > saveSlots || len(km["load"]) > saveSlots {
+		return nil, fmt.Errorf("there are only %d save slots", saveSlots)
+	}
+	return km, km.validate()
+}
+
+// validate makes sure that no key triggers two actions in the same view.
+func (km keyMap) validate() error {
+	for view, actions := range keyContexts {
+		bound := make(map[interface{}]string)
+		if view == editorView {
+			for _, k := range editorSymbolKeys {
+				bound[k] = "editor symbols"
+			}
+		}
+		for _, action := range actions {
+			for _, k := range km[action] {
+				if other, ok := bound[k.value]; ok && other != action {
+					return fmt.Errorf("key %q is bound to both %q and %q", k.label, other, action)
+				}
+				bound[k.value] = action
+			}
+		}
+	}
+	return nil
+}
+
+// label lists the keys of actions for the help bar.
+func (km keyMap) label(actions ...string) string {
+	var labels []string
+	for _, action := range actions {
+		for _, k := range km[action] {
+			labels = append(labels, k.label)
+		}
+	}
+	return strings.Join(labels, "/")
+}
+
+// bind registers handler for all keys of action on view.
+func (g *game) bind(view, action string, handler func(*gocui.Gui, *gocui.View) error) error {
+	for _, k := range g.keys[action] {
+		if err := g.gui.SetKeybinding(view, k.value, gocui.ModNone, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printAction prints the keys of action, unless it has none.
+func (g *game) printAction(action, description string, view *gocui.View) {
+	g.printActions([]string{action}, description, view)
+}
+
+// printActions prints the keys of several actions as one option, like the
+// two directions of browsing.
+func (g *game) printActions(actions []string, description string, view *gocui.View) {
+	if label := g.keys.label(actions...); len(label) > 0 {
+		g.printOption(label, description, view)
+	}
+}
@@ -0,0 +1,127 @@
+package console
+
+import (
+	"github.com/jroimartin/gocui"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    interface{}
+		label   string
+		wantErr bool
+	}{
+		{name: "ctrl-z", want: gocui.KeyCtrlZ, label: "^z"},
+		{name: "Ctrl-Z", want: gocui.KeyCtrlZ, label: "^z"},
+		{name: "ctrl-space", want: gocui.KeyCtrlSpace, label: "^SPACE"},
+		{name: "f1", want: gocui.KeyF1, label: "F1"},
+		{name: "enter", want: gocui.KeyEnter, label: "enter"},
+		{name: "x", want: 'x', label: "x"},
+		{name: "[", want: '[', label: "["},
+		{name: "ctrl-", wantErr: true},
+		{name: "xy", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := parseKey(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseKey(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+			}
+			if k.value != tt.want || k.label != tt.label {
+				t.Errorf("parseKey(%q) = %v %q, want %v %q", tt.name, k.value, k.label, tt.want, tt.label)
+			}
+		})
+	}
+}
+
+func TestDefaultKeyMap(t *testing.T) {
+	km := defaultKeyMap()
+	if err := km.validate(); err != nil {
+		t.Errorf("validate() of the default keys = %v", err)
+	}
+	for view, actions := range keyContexts {
+		for _, action := range actions {
+			if _, ok := defaultKeys[action]; !ok {
+				t.Errorf("action %q of view %q has no default keys", action, view)
+			}
+		}
+	}
+	if got, want := km.label("undo", "redo"), "^z/^u/^y/^r"; got != want {
+		t.Errorf("label() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadKeyMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		action  string
+		label   string
+		wantErr bool
+	}{
+		{name: "missing file", action: "undo", label: "^z/^u"},
+		{name: "rebound", config: `{"undo": ["u", "f9"]}`, action: "undo", label: "u/F9"},
+		{name: "unbound", config: `{"hint": []}`, action: "hint", label: ""},
+		{name: "shared between views", config: `{"first": ["ctrl-n"]}`, action: "first", label: "^n"},
+		{name: "invalid json", config: `{"undo": "u"}`, wantErr: true},
+		{name: "unknown action", config: `{"fly": ["f"]}`, wantErr: true},
+		{name: "unknown key", config: `{"undo": ["ctrl-"]}`, wantErr: true},
+		{name: "collision in game", config: `{"hint": ["ctrl-z"]}`, wantErr: true},
+		{name: "collision in editor", config: `{"editorSave": ["ctrl-n"]}`, wantErr: true},
+		{name: "editor symbol", config: `{"wider": ["#"]}`, wantErr: true},
+		{name: "too many slots", config: `{"save": ["1", "2", "3", "4", "5"]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setConfigDir(t)
+			if tt.config != "" {
+				if err := os.MkdirAll(filepath.Join(dir, "gokoban"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(filepath.Join(dir, "gokoban", keysFile), []byte(tt.config), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			km, err := loadKeyMap(keysFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadKeyMap() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := km.label(tt.action); got != tt.label {
+				t.Errorf("label(%q) = %q, want %q", tt.action, got, tt.label)
+			}
+		})
+	}
+}
+
+// setConfigDir points the user config directory to a temporary one until
+// the test ends and returns it.
+func setConfigDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, key := range []string{"HOME", "XDG_CONFIG_HOME", "AppData"} {
+		old, ok := os.LookupEnv(key)
+		if err := os.Setenv(key, dir); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if ok {
+				_ = os.Setenv(key, old)
+			} else {
+				_ = os.Unsetenv(key)
+			}
+		})
+	}
+	if d, err := os.UserConfigDir(); err == nil {
+		dir = d
+	}
+	return dir
+}
@@ -0,0 +1,283 @@
+package console
+
+import (
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/x-cellent/gokoban/gokoban"
+	"strings"
+)
+
+const (
+	levelSelectionView = "levels"
+	// listWidth is the number of columns of the level list, the thumbnail is
+	// drawn to the right of it.
+	listWidth = 60
+	// maxThumbnailWidth and maxThumbnailHeight bound the thumbnail, larger
+	// levels are scaled down.
+	maxThumbnailWidth  = 40
+	maxThumbnailHeight = 20
+)
+
+// levelSelection is the state of the level selection screen. selected and
+// top are level indexes starting at 0, top is the first listed level.
+type levelSelection struct {
+	selected int
+	top      int
+	solved   []bool
+}
+
+func (g *game) levelSelectionHandler(gui *gocui.Gui, v *gocui.View) error {
+	if g.replaying() || g.level.Completed() || g.playTesting() {
+		return nil
+	}
+	if v != nil {
+		g.openLevelSelection()
+	}
+	return nil
+}
+
+func (g *game) levelSelectionMoveHandler(delta int) func(*gocui.Gui, *gocui.View) error {
+	return func(gui *gocui.Gui, v *gocui.View) error {
+		if v != nil && g.levels != nil {
+			g.selectLevel(g.levels.selected + delta)
+		}
+		return nil
+	}
+}
+
+func (g *game) levelSelectionPageHandler(pages int) func(*gocui.Gui, *gocui.View) error {
+	return func(gui *gocui.Gui, v *gocui.View) error {
+		if v != nil && g.levels != nil {
+			_, h := v.Size()
+			g.selectLevel(g.levels.selected + pages*h)
+		}
+		return nil
+	}
+}
+
+func (g *game) levelSelectionJumpHandler(last bool) func(*gocui.Gui, *gocui.View) error {
+	return func(gui *gocui.Gui, v *gocui.View) error {
+		if v != nil && g.levels != nil {
+			if last {
+				g.selectLevel(g.maxLevel() - 1)
+			} else {
+				g.selectLevel(0)
+			}
+		}
+		return nil
+	}
+}
+
+func (g *game) levelSelectionEnterHandler(gui *gocui.Gui, v *gocui.View) error {
+	if v != nil && g.levels != nil {
+		g.lvl = g.levels.selected + 1
+		if err := g.closeLevelSelection(); err != nil {
+			return err
+		}
+		g.loadLevel()
+	}
+	return nil
+}
+
+func (g *game) levelSelectionCloseHandler(gui *gocui.Gui, v *gocui.View) error {
+	if v != nil {
+		return g.closeLevelSelection()
+	}
+	return nil
+}
+
+func (g *game) levelSelectionKeyBindings() error {
+	if err := g.bind(levelSelectionView, "up", g.levelSelectionMoveHandler(-1)); err != nil {
+		return err
+	}
+	if err := g.bind(levelSelectionView, "down", g.levelSelectionMoveHandler(1)); err != nil {
+		return err
+	}
+	if err := g.bind(levelSelectionView, "pageUp", g.levelSelectionPageHandler(-1)); err != nil {
+		return err
+	}
+	if err := g.bind(levelSelectionView, "pageDown", g.levelSelectionPageHandler(1)); err != nil {
+		return err
+	}
+	if err := g.bind(levelSelectionView, "first", g.levelSelectionJumpHandler(false)); err != nil {
+		return err
+	}
+	if err := g.bind(levelSelectionView, "last", g.levelSelectionJumpHandler(true)); err != nil {
+		return err
+	}
+	if err := g.bind(levelSelectionView, "confirm", g.levelSelectionEnterHandler); err != nil {
+		return err
+	}
+	if err := g.bind(levelSelectionView, "cancel", g.levelSelectionCloseHandler); err != nil {
+		return err
+	}
+	if err := g.bind(levelSelectionView, "levels", g.levelSelectionCloseHandler); err != nil {
+		return err
+	}
+	return nil
+}
+
+// openLevelSelection shows all levels of the collection with the current
+// one selected. The solved state is read once from the profile.
+func (g *game) openLevelSelection() {
+	g.cursor = nil
+	g.levels = &levelSelection{
+		selected: g.lvl - 1,
+		solved:   make([]bool, g.maxLevel()),
+	}
+	if g.profile != nil {
+		for i, l := range g.collection.Levels {
+			if r, err := g.profile.Record(l); err == nil {
+				g.levels.solved[i] = r.Solved()
+			}
+		}
+	}
+	g.update()
+}
+
+func (g *game) closeLevelSelection() error {
+	g.levels = nil
+	if err := g.gui.DeleteView(levelSelectionView); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	if _, err := g.gui.SetCurrentView(g.view); err != nil {
+		return err
+	}
+	g.update()
+	return nil
+}
+
+// selectLevel selects the level with index i, clamped to the collection.
+func (g *game) selectLevel(i int) {
+	if i < 0 {
+		i = 0
+	}
+	if i >= g.maxLevel() {
+		i = g.maxLevel() - 1
+	}
+	g.levels.selected = i
+	g.refresh()
+}
+
+// layoutLevelSelection draws the level selection on top of the game. It is
+// redrawn on every layout so that it follows the selection and the terminal
+// size.
+func (g *game) layoutLevelSelection(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	v, err := gui.SetView(levelSelectionView, 1, 1, maxX-2, maxY-2)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = " Levels "
+		if _, err := gui.SetCurrentView(levelSelectionView); err != nil {
+			return err
+		}
+	}
+	v.Clear()
+	g.printLevelSelection(v)
+	return nil
+}
+
+func (g *game) printLevelSelection(view *gocui.View) {
+	s := g.levels
+	_, h := view.Size()
+	rows := h - 2
+	if rows < 1 {
+		rows = 1
+	}
+	if s.selected < s.top {
+		s.top = s.selected
+	}
+	if s.selected >= s.top+rows {
+		s.top = s.selected - rows + 1
+	}
+
+	thumbnail := g.thumbnail(g.collection.Levels[s.selected])
+	for row := 0; row < rows; row++ {
+		i := s.top + row
+		line := ""
+		if i < g.maxLevel() {
+			line = g.levelLine(i)
+		}
+		line = fmt.Sprintf(" %-*s", listWidth-1, line)
+		if len(line) > listWidth {
+			line = line[:listWidth]
+		}
+		if i == s.selected {
+			_, _ = fmt.Fprint(view, g.theme.paint("selected", line))
+		} else {
+			_, _ = fmt.Fprint(view, line)
+		}
+		if row < len(thumbnail) {
+			_, _ = fmt.Fprint(view, "  ", thumbnail[row])
+		}
+		_, _ = fmt.Fprintln(view)
+	}
+
+	_, _ = fmt.Fprintln(view)
+	_, _ = fmt.Fprint(view, " ")
+	g.printActions([]string{"up", "down"}, "browse", view)
+	g.printActions([]string{"pageUp", "pageDown"}, "page", view)
+	g.printActions([]string{"first", "last"}, "first/last", view)
+	g.printAction("confirm", "play", view)
+	g.printAction("cancel", "back", view)
+	g.printAction("exit", "exit", view)
+}
+
+func (g *game) levelLine(i int) string {
+	l := g.collection.Levels[i]
+	solved := ""
+	if g.levels.solved[i] {
+		solved = "solved"
+	}
+	return fmt.Sprintf("%3d  %3dx%-3d %3d boxes  %-6s  %s", i+1, l.Width(), l.Height(), l.BoxCount(), solved, l.Title)
+}
+
+// thumbnail draws the initial position of l. Levels larger than the
+// thumbnail are scaled down, every character then stands for a square of
+// fields and shows the most important of them.
+func (g *game) thumbnail(l *gokoban.Level) []string {
+	scale := 1
+	for l.Width() > scale*maxThumbnailWidth || l.Height() > scale*maxThumbnailHeight {
+		scale++
+	}
+	pc, pr := l.InitialPlayerPosition()
+
+	var lines []string
+	for r := 0; r < l.Height(); r += scale {
+		var sb strings.Builder
+		for c := 0; c < l.Width(); c += scale {
+			bricks, free, target, box, player := 0, 0, false, false, false
+			for y := r; y < r+scale && y < l.Height(); y++ {
+				for x := c; x < c+scale && x < l.Width(); x++ {
+					switch {
+					case l.IsBrick(x, y):
+						bricks++
+					default:
+						free++
+					}
+					target = target || l.IsTarget(x, y)
+					box = box || l.IsInitialBox(x, y)
+					player = player || (x == pc && y == pr)
+				}
+			}
+			symbol := gokoban.FreeSymbol
+			switch {
+			case player:
+				symbol = gokoban.PlayerSymbol
+			case box && target:
+				symbol = gokoban.BoxOnTargetSymbol
+			case box:
+				symbol = gokoban.BoxSymbol
+			case target:
+				symbol = gokoban.TargetSymbol
+			case bricks > free:
+				symbol = gokoban.BrickSymbol
+			}
+			sb.WriteString(g.theme.field(fieldStyle(symbol)))
+		}
+		lines = append(lines, sb.String())
+	}
+	return lines
+}
@@ -0,0 +1,229 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/x-cellent/gokoban/gokoban"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"unicode"
+)
+
+const (
+	sessionFile = "session.json"
+	saveSlots   = 4
+)
+
+// session is the state of a game that survives a restart. Moves and
+// History refer to the level as rotated by Rotation.
+type session struct {
+	Collection   string   `json:"collection"`
+	Level        int      `json:"level"`
+	Rotation     int      `json:"rotation"`
+	Moves        string   `json:"moves"`
+	History      []string `json:"history,omitempty"`
+	ReplaySpeed  uint     `json:"replaySpeed,omitempty"`
+	ReplayPaused bool     `json:"replayPaused,omitempty"`
+	ReplayIndex  int      `json:"replayIndex,omitempty"`
+	// Reverse games keep their pulls in Moves and the field the player was
+	// placed on in Start. Their history is not saved.
+	Reverse bool              `json:"reverse,omitempty"`
+	Start   *gokoban.Position `json:"start,omitempty"`
+}
+
+func configPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gokoban", name), nil
+}
+
+func slotFile(slot int) string {
+	return fmt.Sprintf("slot%d.json", slot)
+}
+
+// loadSession returns nil without error if there is no such session.
+func loadSession(name string) (*session, error) {
+	path, err := configPath(name)
+	if err != nil {
+		return nil, err
+	}
+	bb, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &session{}
+	if err := json.Unmarshal(bb, s); err != nil {
+		return nil, fmt.Errorf("session %q is not valid: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *session) save(name string) error {
+	path, err := configPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	bb, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, bb, 0644)
+}
+
+func (g *game) session() *session {
+	s := &session{
+		Collection:   g.path,
+		Level:        g.lvl,
+		Rotation:     g.rotation,
+		Moves:        g.level.Moves(),
+		ReplaySpeed:  g.replaySpeed,
+		ReplayPaused: g.replayPaused,
+		ReplayIndex:  g.replayIndex,
+	}
+	if g.level.Reversed() {
+		c, r := g.level.InitialPlayerPosition()
+		s.Reverse = true
+		s.Start = &gokoban.Position{Col: c, Row: r}
+		return s
+	}
+	for _, line := range g.level.HistoryLines() {
+		s.History = append(s.History, g.level.FormatLURD(line))
+	}
+	return s
+}
+
+// saveSession keeps the game for the next start. Games in the level editor
+// are not saved.
+func (g *game) saveSession() {
+	if g.editor == nil {
+		_ = g.session().save(sessionFile)
+	}
+}
+
+// restore continues a saved session, switching the collection if needed.
+// It does not count as a new attempt.
+func (g *game) restore(s *session) error {
+	collection := g.collection
+	if s.Collection != g.path {
+		var err error
+		// Skipped levels were skipped when the session was saved as well.
+		if collection, err = gokoban.OpenCollection(s.Collection); collection == nil {
+			return err
+		}
+	}
+	if s.Level < 1 || s.Level > len(collection.Levels) {
+		return fmt.Errorf("level %d does not exist", s.Level)
+	}
+
+	moves, err := gokoban.ParseLURD(s.Moves)
+	if err != nil {
+		return err
+	}
+	var history [][]gokoban.Course
+	for _, line := range s.History {
+		courses, err := gokoban.ParseLURD(line)
+		if err != nil {
+			return err
+		}
+		history = append(history, courses)
+	}
+
+	g.collection, g.path = collection, s.Collection
+	g.lvl = s.Level
+	g.openLevel()
+	for i := 0; i < s.Rotation%len(rotations); i++ {
+		g.rotate()
+	}
+	if s.Reverse {
+		g.toggleReverse()
+		if s.Start != nil {
+			g.level.PlacePlayer(s.Start.Col, s.Start.Row)
+		}
+		g.playReverse(s.Moves)
+		g.update()
+		return nil
+	}
+	for _, line := range history {
+		g.level.Reset()
+		g.play(line)
+	}
+	g.level.Reset()
+	g.play(moves)
+
+	if g.level.Completed() {
+		g.nextLevel()
+		return nil
+	}
+	if s.ReplaySpeed > 0 && s.ReplayIndex <= len(g.level.Solution) {
+		g.replaySpeed = s.ReplaySpeed
+		g.replayPaused = s.ReplayPaused
+		g.replayIndex = s.ReplayIndex
+		g.startReplay()
+	}
+	g.update()
+	return nil
+}
+
+// playReverse makes the moves of a reverse game outside the command bus.
+// Uppercase steps are pulls. It stops at the first illegal step.
+func (g *game) playReverse(lurd string) {
+	for i := 0; i < len(lurd); i++ {
+		courses, err := gokoban.ParseLURD(lurd[i : i+1])
+		if err != nil {
+			return
+		}
+		course := courses[0]
+		switch {
+		case unicode.IsUpper(rune(lurd[i])) && g.level.CanPull(course):
+			g.level.Pull(course)
+		case !unicode.IsUpper(rune(lurd[i])) && g.level.CanMove(course):
+			g.level.Move(course)
+		default:
+			return
+		}
+	}
+}
+
+// play makes moves outside the command bus and stops at the first illegal
+// one.
+func (g *game) play(courses []gokoban.Course) {
+	for _, course := range courses {
+		if !g.level.CanMove(course) {
+			return
+		}
+		g.level.Move(course)
+	}
+}
+
+func (g *game) saveSlot(slot int) {
+	if err := g.session().save(slotFile(slot)); err != nil {
+		g.flashWarning(fmt.Sprintf("Saving failed: %v", err))
+	} else {
+		g.flashWarning(fmt.Sprintf("Saved to slot %d", slot))
+	}
+	g.update()
+}
+
+func (g *game) loadSlot(slot int) {
+	s, err := loadSession(slotFile(slot))
+	if err == nil && s == nil {
+		err = fmt.Errorf("slot %d is empty", slot)
+	}
+	if err == nil {
+		err = g.restore(s)
+	}
+	if err != nil {
+		g.flashWarning(fmt.Sprintf("Loading failed: %v", err))
+		g.update()
+	}
+}
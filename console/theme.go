@@ -0,0 +1,493 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/nsf/termbox-go"
+	"github.com/x-cellent/gokoban/gokoban"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// themesFile adds themes to the built-in ones, for example
+//
+//	{
+//	  "desert": {
+//	    "brick": {"bg": "#c2b280"},
+//	    "box": {"glyph": "$", "fg": "black", "bg": "172"},
+//	    "boxOnTarget": {"glyph": "*", "fg": "black", "bg": "bright-green"}
+//	  }
+//	}
+//
+// Styles a theme leaves out are taken from the classic theme.
+const themesFile = "themes.json"
+
+type colorDepth int
+
+const (
+	noColors   colorDepth = 0
+	colors16   colorDepth = 16
+	colors256  colorDepth = 256
+	trueColors colorDepth = 1 << 24
+)
+
+func parseColorDepth(s string) (colorDepth, error) {
+	switch strings.ToLower(s) {
+	case "none", "0":
+		return noColors, nil
+	case "16", "8":
+		return colors16, nil
+	case "256":
+		return colors256, nil
+	case "truecolor", "24bit", "24":
+		return trueColors, nil
+	default:
+		return noColors, fmt.Errorf("unknown color mode %q", s)
+	}
+}
+
+// detectColorDepth guesses the colors of the terminal from the environment.
+func detectColorDepth() colorDepth {
+	term := os.Getenv("TERM")
+	switch {
+	case len(os.Getenv("NO_COLOR")) > 0 || term == "dumb":
+		return noColors
+	case os.Getenv("COLORTERM") == "truecolor" || os.Getenv("COLORTERM") == "24bit":
+		return trueColors
+	case strings.Contains(term, "256color"):
+		return colors256
+	default:
+		return colors16
+	}
+}
+
+// supported returns the depth that can be drawn instead of d. True colors
+// are passed through gocui as palette numbers wider than 32 bits.
+func (d colorDepth) supported() colorDepth {
+	if d == trueColors && strconv.IntSize < 64 {
+		return colors256
+	}
+	return d
+}
+
+// newGui starts gocui for depth. gocui only parses escape sequences with
+// up to 256 colors but hands any palette number on to termbox. For true
+// colors termbox is switched to RGB output, which reads the color from
+// that number, see rgbAttribute.
+func (d colorDepth) newGui() (*gocui.Gui, error) {
+	mode := gocui.OutputNormal
+	if d >= colors256 {
+		mode = gocui.Output256
+	}
+	g, err := gocui.NewGui(mode)
+	if err != nil {
+		return nil, err
+	}
+	if d == trueColors {
+		termbox.SetOutputMode(termbox.OutputRGB)
+	}
+	return g, nil
+}
+
+var colorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// color is unset, an index into the 256 color palette or a 24-bit color.
+type color struct {
+	set     bool
+	index   int
+	rgb     bool
+	r, g, b int
+}
+
+// parseColor accepts color names like "red" or "bright-red", palette
+// indexes from 0 to 255 and RGB colors like "#ff8800", which are drawn with
+// the nearest palette color unless the terminal has true colors.
+func parseColor(s string) (color, error) {
+	s = strings.ToLower(s)
+	if len(s) == 0 {
+		return color{}, nil
+	}
+	for i, name := range colorNames {
+		if s == name {
+			return color{set: true, index: i}, nil
+		}
+		if s == "bright-"+name {
+			return color{set: true, index: i + 8}, nil
+		}
+	}
+	if i, err := strconv.Atoi(s); err == nil && i >= 0 && i < 256 {
+		return color{set: true, index: i}, nil
+	}
+	if len(s) == 7 && s[0] == '#' {
+		if v, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return color{set: true, rgb: true, r: int(v >> 16), g: int(v >> 8 & 0xff), b: int(v & 0xff)}, nil
+		}
+	}
+	return color{}, fmt.Errorf("unknown color %q", s)
+}
+
+var basicPalette = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// paletteRGB returns the color of palette index i as xterm shows it.
+func paletteRGB(i int) (int, int, int) {
+	switch {
+	case i < 16:
+		c := basicPalette[i]
+		return c[0], c[1], c[2]
+	case i < 232:
+		levels := []int{0, 95, 135, 175, 215, 255}
+		i -= 16
+		return levels[i/36], levels[i/6%6], levels[i%6]
+	default:
+		v := 8 + 10*(i-232)
+		return v, v, v
+	}
+}
+
+// nearest returns the palette index from first to last-1 closest to the
+// given color.
+func nearest(r, g, b, first, last int) int {
+	best, bestDist := first, -1
+	for i := first; i < last; i++ {
+		pr, pg, pb := paletteRGB(i)
+		dist := (pr-r)*(pr-r) + (pg-g)*(pg-g) + (pb-b)*(pb-b)
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// paletteIndex converts c to a palette index below last. With 256 colors,
+// 24-bit colors are matched against the color cube and the grays only,
+// since terminals change the first 16 colors freely.
+func (c color) paletteIndex(last int) int {
+	switch {
+	case c.rgb && last > 16:
+		return nearest(c.r, c.g, c.b, 16, last)
+	case c.rgb:
+		return nearest(c.r, c.g, c.b, 0, last)
+	case c.index >= last:
+		r, g, b := paletteRGB(c.index)
+		return nearest(r, g, b, 0, last)
+	default:
+		return c.index
+	}
+}
+
+// rgbAttribute returns the palette number gocui turns into the termbox
+// attribute of c in RGB output. Palette colors are drawn as xterm shows
+// them.
+func (c color) rgbAttribute() uint64 {
+	r, g, b := c.r, c.g, c.b
+	if !c.rgb {
+		r, g, b = paletteRGB(c.index)
+	}
+	// gocui adds one to the palette number.
+	return uint64(termbox.RGBToAttribute(uint8(r), uint8(g), uint8(b))) - 1
+}
+
+// style is how a field or a piece of text is drawn. Glyph is a single
+// character and only used for fields.
+type style struct {
+	Glyph     string `json:"glyph,omitempty"`
+	Fg        string `json:"fg,omitempty"`
+	Bg        string `json:"bg,omitempty"`
+	Bold      bool   `json:"bold,omitempty"`
+	Underline bool   `json:"underline,omitempty"`
+	Reverse   bool   `json:"reverse,omitempty"`
+
+	fg color
+	bg color
+}
+
+func (s *style) parse() error {
+	if len(s.Glyph) > 0 && utf8.RuneCountInString(s.Glyph) != 1 {
+		return fmt.Errorf("glyph %q is not a single character", s.Glyph)
+	}
+	var err error
+	if s.fg, err = parseColor(s.Fg); err != nil {
+		return err
+	}
+	s.bg, err = parseColor(s.Bg)
+	return err
+}
+
+// styleNames lists every style a theme sets. The field styles are
+// followed by overlays drawn on top of fields and by text styles.
+var styleNames = []string{
+	"brick", "free", "target", "box", "boxOnTarget", "player", "playerOnTarget",
+	"deadSquare", "deadlock", "cursor", "hint", "problem",
+	"option", "warning", "valid", "selected",
+}
+
+var themes = map[string]map[string]style{
+	"classic": {
+		"brick":          {Glyph: " ", Bg: "yellow"},
+		"free":           {Glyph: " "},
+		"target":         {Glyph: "O", Fg: "green"},
+		"box":            {Glyph: " ", Bg: "blue"},
+		"boxOnTarget":    {Glyph: "*", Fg: "green", Bg: "blue"},
+		"player":         {Glyph: " ", Bg: "white"},
+		"playerOnTarget": {Glyph: "O", Fg: "green", Bg: "white"},
+		"deadSquare":     {Glyph: "·", Fg: "red"},
+		"deadlock":       {Bg: "red"},
+		"cursor":         {Bg: "cyan"},
+		"hint":           {Bg: "magenta"},
+		"problem":        {Bg: "red"},
+		"option":         {Fg: "black", Bg: "white"},
+		"warning":        {Fg: "red"},
+		"valid":          {Fg: "green"},
+		"selected":       {Fg: "black", Bg: "white"},
+	},
+	"modern": {
+		"brick":          {Glyph: " ", Bg: "#8d6e63"},
+		"free":           {Glyph: " "},
+		"target":         {Glyph: ".", Fg: "#66bb6a", Bold: true},
+		"box":            {Glyph: " ", Bg: "#ffb300"},
+		"boxOnTarget":    {Glyph: "*", Fg: "#1b5e20", Bg: "#66bb6a"},
+		"player":         {Glyph: "@", Fg: "#ffffff", Bg: "#1e88e5"},
+		"playerOnTarget": {Glyph: "+", Fg: "#ffffff", Bg: "#43a047"},
+		"deadSquare":     {Glyph: "·", Fg: "#e57373"},
+		"deadlock":       {Bg: "#e53935"},
+		"cursor":         {Bg: "#26c6da"},
+		"hint":           {Bg: "#ab47bc"},
+		"problem":        {Bg: "#e53935"},
+		"option":         {Fg: "#212121", Bg: "#bdbdbd"},
+		"warning":        {Fg: "#ef5350"},
+		"valid":          {Fg: "#66bb6a"},
+		"selected":       {Fg: "#212121", Bg: "#bdbdbd"},
+	},
+	// mono tells fields apart by their glyphs only.
+	"mono": {
+		"brick":          {Glyph: gokoban.BrickSymbol},
+		"free":           {Glyph: gokoban.FreeSymbol},
+		"target":         {Glyph: gokoban.TargetSymbol},
+		"box":            {Glyph: gokoban.BoxSymbol},
+		"boxOnTarget":    {Glyph: gokoban.BoxOnTargetSymbol},
+		"player":         {Glyph: gokoban.PlayerSymbol},
+		"playerOnTarget": {Glyph: gokoban.PlayerOnTargetSymbol},
+		"deadSquare":     {Glyph: "-"},
+		"deadlock":       {Glyph: "X"},
+		"cursor":         {Reverse: true},
+		"hint":           {Underline: true},
+		"problem":        {Glyph: "!"},
+		"option":         {Reverse: true},
+		"warning":        {Bold: true},
+		"valid":          {},
+		"selected":       {Reverse: true},
+	},
+}
+
+// theme draws fields and text with the styles of a named theme in the
+// colors of the terminal.
+type theme struct {
+	name   string
+	depth  colorDepth
+	styles map[string]style
+}
+
+// loadTheme returns the named theme from the built-in ones and the themes
+// file. An empty name selects classic, or mono without colors.
+func loadTheme(name string, depth colorDepth) (*theme, error) {
+	if len(name) == 0 {
+		name = "classic"
+		if depth == noColors {
+			name = "mono"
+		}
+	}
+
+	all := make(map[string]map[string]style)
+	for n, styles := range themes {
+		all[n] = styles
+	}
+	if path, err := configPath(themesFile); err == nil {
+		bb, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			var custom map[string]map[string]style
+			if err := json.Unmarshal(bb, &custom); err != nil {
+				return nil, fmt.Errorf("themes %q are not valid: %w", path, err)
+			}
+			for n, styles := range custom {
+				all[n] = styles
+			}
+		}
+	}
+
+	styles, ok := all[name]
+	if !ok {
+		names := make([]string, 0, len(all))
+		for n := range all {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown theme %q, choose one of %s", name, strings.Join(names, ", "))
+	}
+
+	t := &theme{
+		name:   name,
+		depth:  depth,
+		styles: make(map[string]style),
+	}
+	for _, n := range styleNames {
+		s, ok := styles[n]
+		if !ok {
+			s = themes["classic"][n]
+		}
+		if err := s.parse(); err != nil {
+			return nil, fmt.Errorf("theme %q, style %q: %w", name, n, err)
+		}
+		t.styles[n] = s
+	}
+	for n := range styles {
+		if _, ok := t.styles[n]; !ok {
+			return nil, fmt.Errorf("theme %q: unknown style %q", name, n)
+		}
+	}
+	return t, nil
+}
+
+func defaultTheme() *theme {
+	t, err := loadTheme("classic", colors16)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// escape returns the escape sequence that switches to the colors and
+// attributes given.
+func (t *theme) escape(fg, bg color, bold, underline, reverse bool) string {
+	var attrs []string
+	if bold {
+		attrs = append(attrs, "1")
+	}
+	if underline {
+		attrs = append(attrs, "4")
+	}
+	if reverse {
+		attrs = append(attrs, "7")
+	}
+
+	var sb strings.Builder
+	switch {
+	case t.depth == noColors:
+	case t.depth == colors16:
+		// Bright foreground colors are drawn bold, there are no bright
+		// background colors.
+		if fg.set {
+			i := fg.paletteIndex(16)
+			_, _ = fmt.Fprintf(&sb, "\u001b[%dm", 30+i%8)
+			if i >= 8 && !bold {
+				attrs = append(attrs, "1")
+			}
+		}
+		if bg.set {
+			_, _ = fmt.Fprintf(&sb, "\u001b[%dm", 40+bg.paletteIndex(8))
+		}
+	case t.depth == trueColors:
+		if fg.set {
+			_, _ = fmt.Fprintf(&sb, "\u001b[38;5;%dm", fg.rgbAttribute())
+		}
+		if bg.set {
+			_, _ = fmt.Fprintf(&sb, "\u001b[48;5;%dm", bg.rgbAttribute())
+		}
+	default:
+		// gocui reads a 256 color only from the first three parameters,
+		// so foreground and background need separate sequences.
+		if fg.set {
+			_, _ = fmt.Fprintf(&sb, "\u001b[38;5;%dm", fg.paletteIndex(256))
+		}
+		if bg.set {
+			_, _ = fmt.Fprintf(&sb, "\u001b[48;5;%dm", bg.paletteIndex(256))
+		}
+	}
+	if len(attrs) > 0 {
+		_, _ = fmt.Fprintf(&sb, "\u001b[%sm", strings.Join(attrs, ";"))
+	}
+	return sb.String()
+}
+
+// paint draws text in the named style.
+func (t *theme) paint(name, text string) string {
+	s := t.styles[name]
+	esc := t.escape(s.fg, s.bg, s.Bold, s.Underline, s.Reverse)
+	if len(esc) == 0 {
+		return text
+	}
+	return esc + text + reset
+}
+
+// field draws the named field style. Every overlay replaces the glyph,
+// colors and attributes it sets.
+func (t *theme) field(name string, overlays ...string) string {
+	return t.cell(name, 1, overlays...)
+}
+
+// cell draws a field width columns wide. Bricks repeat their glyph so that
+// walls stay closed, other glyphs are padded with blanks.
+func (t *theme) cell(name string, width int, overlays ...string) string {
+	s := t.styles[name]
+	for _, o := range overlays {
+		o := t.styles[o]
+		if len(o.Glyph) > 0 {
+			s.Glyph = o.Glyph
+		}
+		if o.fg.set {
+			s.fg = o.fg
+		}
+		if o.bg.set {
+			s.bg = o.bg
+		}
+		s.Bold = s.Bold || o.Bold
+		s.Underline = s.Underline || o.Underline
+		s.Reverse = s.Reverse || o.Reverse
+	}
+	glyph := s.Glyph
+	if len(glyph) == 0 {
+		glyph = " "
+	}
+	if name == "brick" {
+		glyph = strings.Repeat(glyph, width)
+	} else {
+		glyph += strings.Repeat(" ", width-1)
+	}
+	esc := t.escape(s.fg, s.bg, s.Bold, s.Underline, s.Reverse)
+	if len(esc) == 0 {
+		return glyph
+	}
+	return esc + glyph + reset
+}
+
+// fieldStyle returns the style name of a level symbol.
+func fieldStyle(symbol string) string {
+	switch symbol {
+	case gokoban.BrickSymbol:
+		return "brick"
+	case gokoban.TargetSymbol:
+		return "target"
+	case gokoban.BoxSymbol:
+		return "box"
+	case gokoban.BoxOnTargetSymbol:
+		return "boxOnTarget"
+	case gokoban.PlayerSymbol:
+		return "player"
+	case gokoban.PlayerOnTargetSymbol:
+		return "playerOnTarget"
+	default:
+		return "free"
+	}
+}
@@ -0,0 +1,107 @@
+package console
+
+import (
+	"github.com/nsf/termbox-go"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseColorDepth(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    colorDepth
+		wantErr bool
+	}{
+		{input: "none", want: noColors},
+		{input: "0", want: noColors},
+		{input: "8", want: colors16},
+		{input: "16", want: colors16},
+		{input: "256", want: colors256},
+		{input: "truecolor", want: trueColors},
+		{input: "24bit", want: trueColors},
+		{input: "None", want: noColors},
+		{input: "true", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseColorDepth(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseColorDepth(%q) = %v, %v, want %v, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    color
+		wantErr bool
+	}{
+		{input: "", want: color{}},
+		{input: "red", want: color{set: true, index: 1}},
+		{input: "Bright-Cyan", want: color{set: true, index: 14}},
+		{input: "208", want: color{set: true, index: 208}},
+		{input: "#ff8800", want: color{set: true, rgb: true, r: 255, g: 136, b: 0}},
+		{input: "256", wantErr: true},
+		{input: "#ff88", wantErr: true},
+		{input: "#gg8800", wantErr: true},
+		{input: "pink", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseColor(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseColor(%q) = %+v, %v, want %+v, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadBuiltinThemes(t *testing.T) {
+	setConfigDir(t)
+	for name := range themes {
+		for _, depth := range []colorDepth{noColors, colors16, colors256, trueColors} {
+			if _, err := loadTheme(name, depth); err != nil {
+				t.Errorf("loadTheme(%q, %d) error = %v", name, depth, err)
+			}
+		}
+	}
+	if _, err := loadTheme("missing", colors256); err == nil {
+		t.Error("loadTheme() of an unknown theme error = nil, want an error")
+	}
+}
+
+func TestEscapeTrueColors(t *testing.T) {
+	th := &theme{depth: trueColors}
+	tests := []struct {
+		name    string
+		c       color
+		r, g, b uint8
+	}{
+		{name: "rgb", c: color{set: true, rgb: true, r: 255, g: 136, b: 0}, r: 255, g: 136, b: 0},
+		{name: "black", c: color{set: true, rgb: true}, r: 0, g: 0, b: 0},
+		{name: "named", c: color{set: true, index: 4}, r: 0, g: 0, b: 238},
+		{name: "palette", c: color{set: true, index: 208}, r: 255, g: 135, b: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			esc := th.escape(tt.c, tt.c, true, false, false)
+			params := strings.Split(strings.Trim(esc, "\u001b[m"), "m\u001b[")
+			if len(params) != 3 || params[2] != "1" {
+				t.Fatalf("escape() = %q, want foreground, background and bold", esc)
+			}
+			for i, prefix := range []string{"38;5;", "48;5;"} {
+				if !strings.HasPrefix(params[i], prefix) {
+					t.Fatalf("escape() = %q, want %q in sequence %d", esc, prefix, i)
+				}
+				// gocui adds one to the palette number it parses.
+				n, err := strconv.ParseUint(strings.TrimPrefix(params[i], prefix), 10, 64)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r, g, b := termbox.AttributeToRGB(termbox.Attribute(n + 1))
+				if r != tt.r || g != tt.g || b != tt.b {
+					t.Errorf("escape() draws %d, %d, %d, want %d, %d, %d", r, g, b, tt.r, tt.g, tt.b)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package console
+
+import (
+	"github.com/jroimartin/gocui"
+	"github.com/x-cellent/gokoban/gokoban"
+	"strings"
+)
+
+// boardTop is the view row of the first board row, below the level info.
+const boardTop = 2
+
+// reservedRows are the view rows besides the board and the HUD: the level
+// info, the blank lines around the HUD, the warning and the options.
+const reservedRows = boardTop + 6
+
+// viewport is the part of the board shown in the game view. camera is the
+// first visible field, x the view column it is drawn at.
+type viewport struct {
+	camera    gokoban.Position
+	cols      int
+	rows      int
+	x         int
+	cellWidth int
+}
+
+// updateViewport fits the board into view. A board that is too large is
+// scrolled so that the player, or the cursor while selecting, stays away
+// from the edges.
+func (g *game) updateViewport(view *gocui.View) {
+	vw, vh := view.Size()
+	hudRows := len(strings.Split(g.level.HUD(), "\n"))
+
+	b := &g.board
+	b.cellWidth = 1
+	if g.wide {
+		b.cellWidth = 2
+	}
+	b.cols = min(g.level.Width(), max(1, vw/b.cellWidth))
+	b.rows = min(g.level.Height(), max(1, vh-reservedRows-hudRows))
+
+	focus := g.playerPosition()
+	if g.cursor != nil {
+		focus = g.cursor.Position
+	}
+	b.camera.Col = follow(b.camera.Col, focus.Col, b.cols, g.level.Width())
+	b.camera.Row = follow(b.camera.Row, focus.Row, b.rows, g.level.Height())
+	b.x = max(0, (vw-b.cols*b.cellWidth)/2)
+}
+
+// follow returns the first visible index of a board dimension of size
+// with visible fields shown. The camera only moves once focus comes closer
+// to an edge than a quarter of the visible fields.
+func follow(camera, focus, visible, size int) int {
+	if size <= visible {
+		return 0
+	}
+	margin := visible / 4
+	if focus < camera+margin {
+		camera = focus - margin
+	}
+	if focus > camera+visible-1-margin {
+		camera = focus - visible + 1 + margin
+	}
+	return max(0, min(camera, size-visible))
+}
+
+// fieldAt returns the field shown at view column x and row y.
+func (g *game) fieldAt(x, y int) (gokoban.Position, bool) {
+	b := g.board
+	if x < b.x || y < boardTop || b.cellWidth == 0 {
+		return gokoban.Position{}, false
+	}
+	col, row := (x-b.x)/b.cellWidth, y-boardTop
+	if col >= b.cols || row >= b.rows {
+		return gokoban.Position{}, false
+	}
+	return gokoban.Position{Col: b.camera.Col + col, Row: b.camera.Row + row}, true
+}
+
+// center indents every line of s to the middle of a view width columns
+// wide.
+func center(s string, width int) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		lines[i] = gokoban.Indent(line, max(0, (width-len(line))/2))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,27 @@
+package console
+
+import (
+	"testing"
+)
+
+func TestFollow(t *testing.T) {
+	tests := []struct {
+		name                         string
+		camera, focus, visible, size int
+		want                         int
+	}{
+		{name: "board fits", camera: 3, focus: 9, visible: 10, size: 10, want: 0},
+		{name: "focus in the middle", camera: 0, focus: 5, visible: 10, size: 30, want: 0},
+		{name: "focus near right edge", camera: 0, focus: 8, visible: 10, size: 30, want: 1},
+		{name: "focus near left edge", camera: 10, focus: 11, visible: 10, size: 30, want: 9},
+		{name: "clamped at start", camera: 5, focus: 0, visible: 10, size: 30, want: 0},
+		{name: "clamped at end", camera: 0, focus: 29, visible: 10, size: 30, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := follow(tt.camera, tt.focus, tt.visible, tt.size); got != tt.want {
+				t.Errorf("follow(%d, %d, %d, %d) = %d, want %d", tt.camera, tt.focus, tt.visible, tt.size, got, tt.want)
+			}
+		})
+	}
+}
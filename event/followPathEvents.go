@@ -0,0 +1,9 @@
+package event
+
+const (
+	OnPathFollowed     = "on-path-followed"
+	OnFollowPathUndone = "on-follow-path-undone"
+)
+
+type PathFollowedEvent struct {
+}
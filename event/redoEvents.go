@@ -0,0 +1,9 @@
+package event
+
+const (
+	OnRedone     = "on-redone"
+	OnRedoUndone = "on-redo-undone"
+)
+
+type RedoneEvent struct {
+}
@@ -0,0 +1,384 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/x-cellent/gokoban/gokoban"
+	"github.com/x-cellent/gokoban/solver"
+	"math/rand"
+	"strings"
+)
+
+const (
+	maxAttempts = 1000
+	wallDensity = 0.15
+	// pullRounds is the number of times a box is picked for pulling per box
+	// in the level.
+	pullRounds = 8
+	// maxPullRun is the maximum number of pulls in a row on the same box.
+	maxPullRun = 3
+	// solveBudget and optimizeBudget are the numbers of positions the
+	// solver and the optimizer may expand to measure a level. Unlike a
+	// timeout they keep the measurement independent of the machine.
+	solveBudget    = 200000
+	optimizeBudget = 200000
+)
+
+var ErrNoLevel = errors.New("no level matching the options found")
+
+type Options struct {
+	// Width and Height include the surrounding bricks.
+	Width  int
+	Height int
+	Boxes  int
+	// MinMoves and MinPushes are lower bounds for the shortest solutions
+	// the solver finds, one of which comes with the generated level.
+	MinMoves  int
+	MinPushes int
+	Seed      int64
+}
+
+func (o Options) validate() error {
+	if o.Width < 5 || o.Height < 5 {
+		return fmt.Errorf("level must be at least 5x5, got %dx%d", o.Width, o.Height)
+	}
+	if o.Boxes < 1 {
+		return fmt.Errorf("level needs at least one box, got %d", o.Boxes)
+	}
+	if inner := (o.Width - 2) * (o.Height - 2); 2*o.Boxes+1 > inner {
+		return fmt.Errorf("%d boxes do not fit into a %dx%d level", o.Boxes, o.Width, o.Height)
+	}
+	return nil
+}
+
+// Generate builds a random level together with a solution. Boxes start on
+// their targets and are pulled away, so the reversed pulls always solve the
+// level. The solver then measures how hard the level really is within a
+// fixed budget, so the same options always produce the same level.
+func Generate(opts Options) (*gokoban.Level, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		r := newRoom(opts.Width, opts.Height, rng)
+		if len(r.floor()) < 2*opts.Boxes+1 {
+			continue
+		}
+		r.placeTargets(opts.Boxes, rng)
+		solution := r.pullBoxes(opts.Boxes*pullRounds, rng)
+		if r.solved() || len(solution) < opts.MinMoves || pushes(solution) < opts.MinPushes {
+			continue
+		}
+
+		level, err := gokoban.LoadLevel(strings.NewReader(r.String()))
+		if err != nil {
+			continue
+		}
+		courses := make([]gokoban.Course, len(solution))
+		for i, m := range solution {
+			courses[i] = m.course
+		}
+		shortest, minMoves, minPushes := measure(level, courses)
+		if minMoves < opts.MinMoves || minPushes < opts.MinPushes {
+			continue
+		}
+		level.SetSolution(shortest)
+		level.Title = fmt.Sprintf("Generated #%d", opts.Seed)
+		return level, nil
+	}
+
+	return nil, ErrNoLevel
+}
+
+// measure returns the solution of level with the fewest moves and the
+// fewest moves and pushes of any solution found. The pulled solution is
+// only an upper bound, so a push optimal one is searched as well and both
+// are shortened by the optimizer.
+func measure(level *gokoban.Level, pulled []gokoban.Course) (shortest []gokoban.Course, moves, pushes int) {
+	ctx := context.Background()
+	solutions := [][]gokoban.Course{pulled}
+	if s, err := solver.SolveBudget(ctx, level, solver.PushOptimal, solveBudget); err == nil {
+		solutions = append(solutions, s)
+	}
+	for i, s := range solutions {
+		if optimized, err := solver.OptimizeBudget(ctx, level, s, optimizeBudget); err == nil {
+			s = optimized
+		}
+		v := level.VerifySolution(s)
+		if i == 0 || v.Moves < moves {
+			shortest, moves = s, v.Moves
+		}
+		if i == 0 || v.Pushes < pushes {
+			pushes = v.Pushes
+		}
+	}
+	return shortest, moves, pushes
+}
+
+type step struct {
+	course gokoban.Course
+	push   bool
+}
+
+func pushes(steps []step) int {
+	n := 0
+	for _, s := range steps {
+		if s.push {
+			n++
+		}
+	}
+	return n
+}
+
+var courses = []gokoban.Course{gokoban.Up, gokoban.Right, gokoban.Down, gokoban.Left}
+
+type room struct {
+	width   int
+	height  int
+	walls   []bool
+	targets []bool
+	boxes   []bool
+	player  int
+}
+
+func newRoom(width, height int, rng *rand.Rand) *room {
+	r := &room{
+		width:   width,
+		height:  height,
+		walls:   make([]bool, width*height),
+		targets: make([]bool, width*height),
+		boxes:   make([]bool, width*height),
+	}
+	for pos := range r.walls {
+		c, row := pos%width, pos/width
+		border := c == 0 || row == 0 || c == width-1 || row == height-1
+		r.walls[pos] = border || rng.Float64() < wallDensity
+	}
+	r.keepLargestArea()
+	return r
+}
+
+func (r *room) delta(c gokoban.Course) int {
+	switch c {
+	case gokoban.Up:
+		return -r.width
+	case gokoban.Right:
+		return 1
+	case gokoban.Down:
+		return r.width
+	default:
+		return -1
+	}
+}
+
+func (r *room) floor() []int {
+	var floor []int
+	for pos, wall := range r.walls {
+		if !wall {
+			floor = append(floor, pos)
+		}
+	}
+	return floor
+}
+
+// area returns all floor cells connected to pos, ignoring boxes if
+// withBoxes is false.
+func (r *room) area(pos int, withBoxes bool) []bool {
+	area := make([]bool, len(r.walls))
+	area[pos] = true
+	queue := []int{pos}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, c := range courses {
+			to := p + r.delta(c)
+			if area[to] || r.walls[to] || (withBoxes && r.boxes[to]) {
+				continue
+			}
+			area[to] = true
+			queue = append(queue, to)
+		}
+	}
+	return area
+}
+
+// keepLargestArea turns every floor cell outside the largest connected area
+// into a brick.
+func (r *room) keepLargestArea() {
+	var largest []bool
+	size := 0
+	seen := make([]bool, len(r.walls))
+	for _, pos := range r.floor() {
+		if seen[pos] {
+			continue
+		}
+		area := r.area(pos, false)
+		n := 0
+		for p, in := range area {
+			if in {
+				seen[p] = true
+				n++
+			}
+		}
+		if n > size {
+			largest, size = area, n
+		}
+	}
+	for pos := range r.walls {
+		r.walls[pos] = largest == nil || !largest[pos]
+	}
+}
+
+func (r *room) placeTargets(n int, rng *rand.Rand) {
+	floor := r.floor()
+	rng.Shuffle(len(floor), func(i, j int) {
+		floor[i], floor[j] = floor[j], floor[i]
+	})
+	for _, pos := range floor[:n] {
+		r.targets[pos] = true
+		r.boxes[pos] = true
+	}
+	r.player = floor[n]
+}
+
+func (r *room) solved() bool {
+	for pos, isBox := range r.boxes {
+		if isBox && !r.targets[pos] {
+			return false
+		}
+	}
+	return true
+}
+
+type pull struct {
+	box    int
+	course gokoban.Course
+}
+
+// pullBoxes plays reverse Sokoban: the player walks up to a box and pulls
+// it. It returns the forward solution of the resulting position.
+func (r *room) pullBoxes(rounds int, rng *rand.Rand) []step {
+	var reverse []step
+	for i := 0; i < rounds; i++ {
+		pulls := r.possiblePulls()
+		if len(pulls) == 0 {
+			break
+		}
+		p := pulls[rng.Intn(len(pulls))]
+		d := r.delta(p.course)
+
+		for _, c := range r.walk(r.player, p.box+d) {
+			reverse = append(reverse, step{course: c})
+		}
+		r.player = p.box + d
+
+		for run := 1 + rng.Intn(maxPullRun); run > 0 && r.canPull(r.player-d, p.course); run-- {
+			box := r.player - d
+			r.boxes[box] = false
+			r.boxes[r.player] = true
+			r.player += d
+			reverse = append(reverse, step{course: p.course, push: true})
+		}
+	}
+
+	forward := make([]step, len(reverse))
+	for i, s := range reverse {
+		forward[len(reverse)-1-i] = step{
+			course: s.course.Opposite(),
+			push:   s.push,
+		}
+	}
+	return forward
+}
+
+// possiblePulls lists all boxes the player can reach and pull together with
+// the course the player walks while pulling.
+func (r *room) possiblePulls() []pull {
+	reach := r.area(r.player, true)
+	var pulls []pull
+	for pos, isBox := range r.boxes {
+		if !isBox {
+			continue
+		}
+		for _, c := range courses {
+			stand := pos + r.delta(c)
+			if reach[stand] && r.canPull(pos, c) {
+				pulls = append(pulls, pull{box: pos, course: c})
+			}
+		}
+	}
+	return pulls
+}
+
+// canPull reports whether the box at pos can be pulled one step in course
+// by a player standing next to it.
+func (r *room) canPull(pos int, c gokoban.Course) bool {
+	d := r.delta(c)
+	stand, to := pos+d, pos+2*d
+	if !r.boxes[pos] || r.walls[stand] || r.boxes[stand] {
+		return false
+	}
+	return !r.walls[to] && !r.boxes[to]
+}
+
+func (r *room) walk(from, to int) []gokoban.Course {
+	prev := make([]int, len(r.walls))
+	for p := range prev {
+		prev[p] = -1
+	}
+	prev[from] = from
+	queue := []int{from}
+	for len(queue) > 0 && prev[to] < 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, c := range courses {
+			n := p + r.delta(c)
+			if prev[n] >= 0 || r.walls[n] || r.boxes[n] {
+				continue
+			}
+			prev[n] = p
+			queue = append(queue, n)
+		}
+	}
+
+	var path []gokoban.Course
+	for p := to; p != from; p = prev[p] {
+		for _, c := range courses {
+			if prev[p]+r.delta(c) == p {
+				path = append([]gokoban.Course{c}, path...)
+				break
+			}
+		}
+	}
+	return path
+}
+
+func (r *room) String() string {
+	var sb strings.Builder
+	for pos := range r.walls {
+		if pos > 0 && pos%r.width == 0 {
+			sb.WriteString("\n")
+		}
+		switch {
+		case r.walls[pos]:
+			sb.WriteString(gokoban.BrickSymbol)
+		case pos == r.player && r.targets[pos]:
+			sb.WriteString(gokoban.PlayerOnTargetSymbol)
+		case pos == r.player:
+			sb.WriteString(gokoban.PlayerSymbol)
+		case r.boxes[pos] && r.targets[pos]:
+			sb.WriteString(gokoban.BoxOnTargetSymbol)
+		case r.boxes[pos]:
+			sb.WriteString(gokoban.BoxSymbol)
+		case r.targets[pos]:
+			sb.WriteString(gokoban.TargetSymbol)
+		default:
+			sb.WriteString(gokoban.FreeSymbol)
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
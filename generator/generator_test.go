@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"testing"
+)
+
+func TestGenerateInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "too narrow", opts: Options{Width: 4, Height: 8, Boxes: 1}},
+		{name: "too low", opts: Options{Width: 8, Height: 4, Boxes: 1}},
+		{name: "no boxes", opts: Options{Width: 8, Height: 8}},
+		{name: "too many boxes", opts: Options{Width: 5, Height: 5, Boxes: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if l, err := Generate(tt.opts); err == nil {
+				t.Errorf("Generate() = %v, want an error", l)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "single box", opts: Options{Width: 6, Height: 6, Boxes: 1, Seed: 1}},
+		{name: "few boxes", opts: Options{Width: 8, Height: 7, Boxes: 2, Seed: 2}},
+		{name: "minimum solution", opts: Options{Width: 8, Height: 8, Boxes: 2, MinMoves: 15, MinPushes: 5, Seed: 3}},
+		{name: "many boxes", opts: Options{Width: 12, Height: 10, Boxes: 6, Seed: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := Generate(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Width() > tt.opts.Width || l.Height() > tt.opts.Height {
+				t.Errorf("Generate() = %dx%d level, want at most %dx%d", l.Width(), l.Height(), tt.opts.Width, tt.opts.Height)
+			}
+			v := l.Verify()
+			if !v.Valid {
+				t.Fatalf("solution fails at step %d: %v", v.Step, v.Err)
+			}
+			if v.Moves < tt.opts.MinMoves || v.Pushes < tt.opts.MinPushes {
+				t.Errorf("solution has %d moves and %d pushes, want at least %d and %d", v.Moves, v.Pushes, tt.opts.MinMoves, tt.opts.MinPushes)
+			}
+
+			again, err := Generate(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.String() != l.String() {
+				t.Errorf("Generate() with the same options =\n%v\nwant\n%v", again, l)
+			}
+			if got, want := again.FormatLURD(again.Solution), l.FormatLURD(l.Solution); got != want {
+				t.Errorf("solution with the same options = %q, want %q", got, want)
+			}
+		})
+	}
+}
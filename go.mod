@@ -1,11 +1,10 @@
 module github.com/x-cellent/gokoban
 
 require (
-	github.com/jroimartin/gocui v0.3.0
-	github.com/mattn/go-runewidth v0.0.4 // indirect
-	github.com/nsf/termbox-go v0.0.0-20190817171036-93860e161317 // indirect
+	github.com/jroimartin/gocui v0.5.0
+	github.com/nsf/termbox-go v1.1.1
 	github.com/x-cellent/decs v0.0.2
 	go.uber.org/zap v1.9.1
 )
 
-go 1.13
+go 1.16
@@ -0,0 +1,351 @@
+package gokoban
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Collection struct {
+	Title   string
+	Author  string
+	Comment string
+	Levels  []*Level
+}
+
+func LoadCollectionFS(fsys fs.FS, name string) (*Collection, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseCollection(f)
+}
+
+// ParseCollection reads a multi-level XSB/SOK file. Levels are separated by
+// at least one non-board line, ';' lines are comments and the keys Title,
+// Author, Comment and Solution are recognized. Metadata before the first
+// level belongs to the collection, afterwards to the preceding level.
+//
+// Invalid levels are skipped together with their metadata and invalid
+// solutions are dropped. As long as any level is valid, the collection is
+// returned together with CollectionErrors listing those problems.
+func ParseCollection(r io.Reader) (*Collection, error) {
+	p := &collectionParser{
+		collection: &Collection{},
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.parseLine(strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	p.closeSolution()
+	p.flush()
+	if p.level != nil && !p.skipped {
+		p.level.Comment = joinComment(p.level.Comment, p.pending...)
+	}
+
+	return p.result("collection")
+}
+
+// LoadDirFS reads a directory in the bundled layout: level1.txt, level2.txt, ...
+// with optional solution1.txt, solution2.txt, ... Problems are handled like
+// in ParseCollection.
+func LoadDirFS(fsys fs.FS) (*Collection, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	max := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "level") || !strings.HasSuffix(name, ".txt") {
+			continue
+		}
+		if lvl, err := strconv.Atoi(name[5 : len(name)-4]); err == nil && max < lvl {
+			max = lvl
+		}
+	}
+
+	if max == 0 {
+		return nil, fmt.Errorf("directory contains no levels")
+	}
+
+	p := &collectionParser{
+		collection: &Collection{},
+	}
+	for lvl := 1; lvl <= max; lvl++ {
+		level, err := LoadLevelFS(fsys, fmt.Sprintf("level%d.txt", lvl))
+		if err != nil {
+			p.fail(lvl, true, err)
+			continue
+		}
+		err = level.LoadSolutionFS(fsys, fmt.Sprintf("solution%d.txt", lvl))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			p.fail(lvl, false, err)
+		}
+		p.collection.Levels = append(p.collection.Levels, level)
+	}
+
+	return p.result("directory")
+}
+
+type collectionParser struct {
+	collection *Collection
+	// level is the last valid level, n counts all levels so far and skipped
+	// is set while the metadata of an invalid level is read.
+	level    *Level
+	n        int
+	skipped  bool
+	errs     CollectionErrors
+	board    []string
+	pending  []string
+	solving  bool
+	solution []string
+	comment  *strings.Builder
+}
+
+func (p *collectionParser) parseLine(line string) {
+	if p.comment != nil {
+		if strings.EqualFold(strings.TrimSpace(line), "comment-end:") {
+			p.setComment(p.comment.String())
+			p.comment = nil
+			return
+		}
+		if p.comment.Len() > 0 {
+			p.comment.WriteString("\n")
+		}
+		p.comment.WriteString(line)
+		return
+	}
+
+	trimmed := strings.TrimSpace(line)
+	if p.solving && len(trimmed) > 0 && isSolutionLine(trimmed) {
+		p.solution = append(p.solution, trimmed)
+		return
+	}
+	p.closeSolution()
+
+	if isBoardLine(line) {
+		p.board = append(p.board, line)
+		return
+	}
+
+	p.flush()
+
+	if len(trimmed) == 0 {
+		return
+	}
+
+	if strings.HasPrefix(trimmed, ";") {
+		p.pending = append(p.pending, strings.TrimSpace(trimmed[1:]))
+		return
+	}
+
+	key, value := splitKeyValue(trimmed)
+	switch {
+	case strings.EqualFold(key, "title"):
+		p.setTitle(value)
+	case strings.EqualFold(key, "author"):
+		p.setAuthor(value)
+	case strings.EqualFold(key, "comment"):
+		if len(value) > 0 {
+			p.setComment(value)
+		} else {
+			p.comment = &strings.Builder{}
+		}
+	case strings.HasPrefix(strings.ToLower(trimmed), "solution") && p.level != nil && !p.skipped:
+		p.solving = true
+		if i := strings.Index(trimmed, ":"); i >= 0 && len(strings.TrimSpace(trimmed[i+1:])) > 0 {
+			p.solution = append(p.solution, strings.TrimSpace(trimmed[i+1:]))
+		}
+	case len(key) == 0 && p.level != nil && !p.skipped && len(p.level.Title) == 0:
+		p.level.Title = trimmed
+	default:
+		p.setComment(trimmed)
+	}
+}
+
+func (p *collectionParser) flush() {
+	if len(p.board) == 0 {
+		return
+	}
+
+	p.n++
+	level, err := parseLevel(normalizeBoard(p.board))
+	p.board = nil
+	p.skipped = err != nil
+	if err != nil {
+		p.fail(p.n, true, err)
+		p.pending = nil
+		return
+	}
+	level.Comment = joinComment(level.Comment, p.pending...)
+
+	p.collection.Levels = append(p.collection.Levels, level)
+	p.level = level
+	p.pending = nil
+}
+
+func (p *collectionParser) fail(level int, skipped bool, err error) {
+	p.errs = append(p.errs, &CollectionError{Level: level, Skipped: skipped, Err: err})
+}
+
+// result returns the collection with the problems found, or only an error
+// if no valid level is left.
+func (p *collectionParser) result(kind string) (*Collection, error) {
+	if len(p.collection.Levels) == 0 {
+		if len(p.errs) > 0 {
+			return nil, fmt.Errorf("%s contains no valid levels: %w", kind, p.errs)
+		}
+		return nil, fmt.Errorf("%s contains no levels", kind)
+	}
+	if len(p.errs) > 0 {
+		return p.collection, p.errs
+	}
+	return p.collection, nil
+}
+
+// closeSolution parses the open solution block once it ends at a blank or
+// non-solution line. Lines after the one that completes the level are
+// comments that happen to consist of LURD letters only, like "Dull".
+func (p *collectionParser) closeSolution() {
+	if !p.solving {
+		return
+	}
+	lines := p.solution
+	p.solving, p.solution = false, nil
+
+	n := solutionLength(p.level, lines)
+	err := p.level.LoadSolution(strings.NewReader(strings.Join(lines[:n], "\n")))
+	if err != nil {
+		p.fail(p.n, false, err)
+	}
+	for _, line := range lines[n:] {
+		p.setComment(line)
+	}
+}
+
+// solutionLength returns the number of lines needed to complete l. If they
+// never do, all lines are kept so that LoadSolution reports the problem.
+func solutionLength(l *Level, lines []string) int {
+	l = l.Clone()
+	for i, line := range lines {
+		for j := 0; j < len(line); j++ {
+			c, _, err := parseCourse(line[j])
+			if err != nil || !l.CanMove(c) {
+				return len(lines)
+			}
+			l.Move(c)
+		}
+		if l.Completed() {
+			return i + 1
+		}
+	}
+	return len(lines)
+}
+
+func (p *collectionParser) setTitle(title string) {
+	if p.skipped {
+		return
+	}
+	if p.level == nil {
+		p.collection.Title = title
+		return
+	}
+	p.level.Title = title
+}
+
+func (p *collectionParser) setAuthor(author string) {
+	if p.skipped {
+		return
+	}
+	if p.level == nil {
+		p.collection.Author = author
+		return
+	}
+	p.level.Author = author
+}
+
+func (p *collectionParser) setComment(comment string) {
+	if p.skipped {
+		return
+	}
+	if p.level == nil {
+		p.collection.Comment = joinComment(p.collection.Comment, comment)
+		return
+	}
+	p.level.Comment = joinComment(p.level.Comment, comment)
+}
+
+func isBoardLine(line string) bool {
+	if !strings.Contains(line, BrickSymbol) {
+		return false
+	}
+	for _, r := range line {
+		switch string(r) {
+		case BrickSymbol, TargetSymbol, BoxSymbol, BoxOnTargetSymbol, PlayerSymbol, PlayerOnTargetSymbol, FreeSymbol, "-", "_":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isSolutionLine(line string) bool {
+	for i := 0; i < len(line); i++ {
+		if _, _, err := parseCourse(line[i]); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeBoard(board []string) []string {
+	lines := make([]string, len(board))
+	for i, line := range board {
+		lines[i] = strings.NewReplacer("-", FreeSymbol, "_", FreeSymbol).Replace(line)
+	}
+	return lines
+}
+
+func splitKeyValue(line string) (string, string) {
+	i := strings.Index(line, ":")
+	if i <= 0 || strings.ContainsAny(line[:i], " \t") {
+		return "", line
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+}
+
+func joinComment(comment string, lines ...string) string {
+	for _, line := range lines {
+		if len(comment) > 0 {
+			comment += "\n"
+		}
+		comment += line
+	}
+	return comment
+}
+
+// OpenCollection loads either a collection file or a level directory.
+func OpenCollection(path string) (*Collection, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return LoadDirFS(os.DirFS(path))
+	}
+	return LoadCollectionFS(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+}
@@ -0,0 +1,149 @@
+package gokoban
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseCollectionSolution(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantSolution string
+		wantComment  string
+	}{
+		{
+			name:         "single line",
+			input:        "######\n#@ $.#\n######\nSolution: rR\n",
+			wantSolution: "rR",
+		},
+		{
+			name:         "wrapped",
+			input:        "######\n#@ $.#\n######\nSolution:\nr\nR\n",
+			wantSolution: "rR",
+		},
+		{
+			name:         "LURD comment after solution",
+			input:        "######\n#@ $.#\n######\nSolution:\nr\nR\nDull\n",
+			wantSolution: "rR",
+			wantComment:  "Dull",
+		},
+		{
+			name:         "blank line ends solution",
+			input:        "######\n#@ $.#\n######\nTitle: One\nSolution: rR\n\nDrud\n",
+			wantSolution: "rR",
+			wantComment:  "Drud",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCollection(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := c.Levels[0]
+			if got := l.FormatLURD(l.Solution); got != tt.wantSolution {
+				t.Errorf("solution = %q, want %q", got, tt.wantSolution)
+			}
+			if l.Comment != tt.wantComment {
+				t.Errorf("comment = %q, want %q", l.Comment, tt.wantComment)
+			}
+		})
+	}
+}
+
+func TestParseCollectionSkipsInvalidLevels(t *testing.T) {
+	valid := "######\n#@ $.#\n######\n"
+	invalid := "######\n#@@$.#\n######\n"
+	tests := []struct {
+		name       string
+		input      string
+		wantTitles []string
+		wantErrs   []CollectionError
+		wantErr    error
+	}{
+		{
+			name:       "all valid",
+			input:      valid + "Title: One\n\n" + valid + "Title: Two\n",
+			wantTitles: []string{"One", "Two"},
+		},
+		{
+			name:       "invalid level with metadata",
+			input:      valid + "Title: One\n\n" + invalid + "Title: Bad\nSolution: rR\n\n" + valid + "Title: Three\n",
+			wantTitles: []string{"One", "Three"},
+			wantErrs:   []CollectionError{{Level: 2, Skipped: true, Err: ErrMultiplePlayers}},
+			wantErr:    ErrMultiplePlayers,
+		},
+		{
+			name:       "invalid solution",
+			input:      valid + "Title: One\nSolution: rRx\n\n" + valid + "Title: Two\n",
+			wantTitles: []string{"One", "Two"},
+			wantErrs:   []CollectionError{{Level: 1, Skipped: false, Err: ErrBadSolutionChar}},
+			wantErr:    ErrBadSolutionChar,
+		},
+		{
+			name:    "no valid level",
+			input:   invalid,
+			wantErr: ErrMultiplePlayers,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCollection(strings.NewReader(tt.input))
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseCollection() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantTitles == nil {
+				if c != nil {
+					t.Errorf("ParseCollection() = %d levels, want none", len(c.Levels))
+				}
+				return
+			}
+
+			var titles []string
+			for _, l := range c.Levels {
+				titles = append(titles, l.Title)
+			}
+			if !reflect.DeepEqual(titles, tt.wantTitles) {
+				t.Errorf("titles = %v, want %v", titles, tt.wantTitles)
+			}
+			var errs CollectionErrors
+			errors.As(err, &errs)
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("errors = %v, want %d", errs, len(tt.wantErrs))
+			}
+			for i, e := range errs {
+				want := tt.wantErrs[i]
+				if e.Level != want.Level || e.Skipped != want.Skipped || !errors.Is(e, want.Err) {
+					t.Errorf("error %d = %+v, want %+v", i, *e, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDirFSSkipsInvalidLevels(t *testing.T) {
+	fsys := fstest.MapFS{
+		"level1.txt":    {Data: []byte("######\n#@ $.#\n######\n")},
+		"level2.txt":    {Data: []byte("######\n#@ $ #\n######\n")},
+		"level3.txt":    {Data: []byte("######\n#@ $.#\n######\n")},
+		"solution3.txt": {Data: []byte("Rr")},
+	}
+	c, err := LoadDirFS(fsys)
+	var errs CollectionErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("LoadDirFS() error = %v, want two problems", err)
+	}
+	if errs[0].Level != 2 || !errs[0].Skipped || !errors.Is(errs[0], ErrBoxTargetMismatch) {
+		t.Errorf("first problem = %v, want level 2 skipped", errs[0])
+	}
+	if errs[1].Level != 3 || errs[1].Skipped || !errors.Is(errs[1], ErrPushMismatch) {
+		t.Errorf("second problem = %v, want solution 3 dropped", errs[1])
+	}
+	if len(c.Levels) != 2 || len(c.Levels[1].Solution) != 0 {
+		t.Errorf("LoadDirFS() = %d levels, want 2 with the second unsolved", len(c.Levels))
+	}
+}
@@ -0,0 +1,218 @@
+package gokoban
+
+type DeadlockKind int
+
+const (
+	// DeadSquare is a box on a square from which no target can be reached.
+	DeadSquare DeadlockKind = iota
+	// FreezeDeadlock is a box off target that can neither move horizontally
+	// nor vertically because of walls, dead squares or other frozen boxes.
+	FreezeDeadlock
+	// BlockDeadlock is a 2x2 square of boxes and bricks with at least one
+	// box off target.
+	BlockDeadlock
+)
+
+func (k DeadlockKind) String() string {
+	switch k {
+	case DeadSquare:
+		return "dead square"
+	case FreezeDeadlock:
+		return "freeze"
+	default:
+		return "2x2 block"
+	}
+}
+
+type Position struct {
+	Col int
+	Row int
+}
+
+func (p Position) Neighbor(course Course) Position {
+	dc, dr := getRelativeMovement(course)
+	return Position{Col: p.Col + dc, Row: p.Row + dr}
+}
+
+type Deadlock struct {
+	Kind  DeadlockKind
+	Boxes []Position
+}
+
+// IsDeadSquare reports whether a box pushed onto the given floor field can
+// never reach any target.
+func (l *Level) IsDeadSquare(col, row int) bool {
+	if col < 0 || row < 0 || col >= l.width || row >= l.height {
+		return false
+	}
+	return l.dead[l.pos(col, row)]
+}
+
+func (l *Level) Deadlocked() bool {
+	return len(l.Deadlocks()) > 0
+}
+
+// Deadlocks lists every deadlock of the current position. Each box is
+// reported at most once, by the first matching kind in the order
+// BlockDeadlock, DeadSquare, FreezeDeadlock. Reverse levels never report
+// deadlocks since pulled boxes are not stuck in the same way.
+func (l *Level) Deadlocks() []Deadlock {
+	if l.reverse {
+		return nil
+	}
+
+	var deadlocks []Deadlock
+	reported := make(map[Position]bool)
+
+	for r := 0; r < l.height-1; r++ {
+		for c := 0; c < l.width-1; c++ {
+			if boxes, ok := l.block(c, r); ok {
+				deadlocks = append(deadlocks, Deadlock{
+					Kind:  BlockDeadlock,
+					Boxes: boxes,
+				})
+				for _, p := range boxes {
+					reported[p] = true
+				}
+			}
+		}
+	}
+
+	for r := 0; r < l.height; r++ {
+		for c := 0; c < l.width; c++ {
+			p := Position{Col: c, Row: r}
+			if reported[p] || !l.IsBox(c, r) || l.IsTarget(c, r) {
+				continue
+			}
+			kind := DeadSquare
+			if !l.IsDeadSquare(c, r) {
+				if !l.frozen(c, r) {
+					continue
+				}
+				kind = FreezeDeadlock
+			}
+			deadlocks = append(deadlocks, Deadlock{
+				Kind:  kind,
+				Boxes: []Position{p},
+			})
+			reported[p] = true
+		}
+	}
+
+	return deadlocks
+}
+
+func (l *Level) isWall(col, row int) bool {
+	pos, ok := l.index(col, row)
+	return !ok || l.kinds[pos] == brick
+}
+
+// block checks the 2x2 square with its upper left corner at col, row.
+func (l *Level) block(col, row int) ([]Position, bool) {
+	var boxes []Position
+	offTarget := false
+	for _, p := range []Position{{col, row}, {col + 1, row}, {col, row + 1}, {col + 1, row + 1}} {
+		if l.IsBox(p.Col, p.Row) {
+			boxes = append(boxes, p)
+			offTarget = offTarget || !l.IsTarget(p.Col, p.Row)
+			continue
+		}
+		if !l.isWall(p.Col, p.Row) {
+			return nil, false
+		}
+	}
+	return boxes, offTarget
+}
+
+func (l *Level) frozen(col, row int) bool {
+	return l.frozenAxis(col, row, true, make(map[Position]bool)) &&
+		l.frozenAxis(col, row, false, make(map[Position]bool))
+}
+
+// frozenAxis reports whether the box at col, row is blocked along one
+// axis. Boxes already looked at are treated as walls to break cycles.
+func (l *Level) frozenAxis(col, row int, horizontal bool, seen map[Position]bool) bool {
+	seen[Position{col, row}] = true
+
+	dc, dr := 0, 1
+	if horizontal {
+		dc, dr = 1, 0
+	}
+	before := Position{col - dc, row - dr}
+	after := Position{col + dc, row + dr}
+
+	if l.isWall(before.Col, before.Row) || l.isWall(after.Col, after.Row) || seen[before] || seen[after] {
+		return true
+	}
+	if l.IsDeadSquare(before.Col, before.Row) && l.IsDeadSquare(after.Col, after.Row) {
+		return true
+	}
+	for _, p := range []Position{before, after} {
+		if l.IsBox(p.Col, p.Row) && l.frozenAxis(p.Col, p.Row, !horizontal, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// deadSquares pulls a box backwards from every target. Floor fields inside
+// the level that are never reached are dead. Reverse levels have none.
+func (l *Level) deadSquares() []bool {
+	if l.reverse {
+		return make([]bool, l.width*l.height)
+	}
+
+	alive := make([]bool, l.width*l.height)
+	var queue []Position
+	for r := 0; r < l.height; r++ {
+		for c := 0; c < l.width; c++ {
+			if l.IsTarget(c, r) {
+				alive[l.pos(c, r)] = true
+				queue = append(queue, Position{c, r})
+			}
+		}
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, course := range []Course{Up, Right, Down, Left} {
+			dc, dr := getRelativeMovement(course)
+			to := Position{p.Col - dc, p.Row - dr}
+			if l.isWall(to.Col, to.Row) || l.isWall(to.Col-dc, to.Row-dr) || alive[l.pos(to.Col, to.Row)] {
+				continue
+			}
+			alive[l.pos(to.Col, to.Row)] = true
+			queue = append(queue, to)
+		}
+	}
+
+	dead := make([]bool, len(alive))
+	inside := l.inside()
+	for i := range dead {
+		dead[i] = inside[i] && !alive[i]
+	}
+	return dead
+}
+
+// inside marks all non-brick fields reachable from the initial player
+// position, ignoring boxes.
+func (l *Level) inside() []bool {
+	inside := make([]bool, l.width*l.height)
+	pc, pr := l.InitialPlayerPosition()
+	inside[l.pos(pc, pr)] = true
+	queue := []Position{{pc, pr}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, course := range []Course{Up, Right, Down, Left} {
+			dc, dr := getRelativeMovement(course)
+			to := Position{p.Col + dc, p.Row + dr}
+			if l.isWall(to.Col, to.Row) || inside[l.pos(to.Col, to.Row)] {
+				continue
+			}
+			inside[l.pos(to.Col, to.Row)] = true
+			queue = append(queue, to)
+		}
+	}
+	return inside
+}
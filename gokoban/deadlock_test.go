@@ -0,0 +1,84 @@
+package gokoban
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeadlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Deadlock
+	}{
+		{
+			name:  "none",
+			input: "#######\n#@ $ .#\n#######",
+		},
+		{
+			name:  "dead square",
+			input: "#######\n#  $  #\n#@   .#\n#######",
+			want:  []Deadlock{{Kind: DeadSquare, Boxes: []Position{{3, 1}}}},
+		},
+		{
+			name:  "freeze",
+			input: "#######\n#.. # #\n#  $$ #\n#@  # #\n#######",
+			want: []Deadlock{
+				{Kind: FreezeDeadlock, Boxes: []Position{{3, 2}}},
+				{Kind: FreezeDeadlock, Boxes: []Position{{4, 2}}},
+			},
+		},
+		{
+			name:  "2x2 block",
+			input: "########\n#  $$..#\n#@     #\n########",
+			want:  []Deadlock{{Kind: BlockDeadlock, Boxes: []Position{{3, 1}, {4, 1}}}},
+		},
+		{
+			name:  "2x2 block on targets",
+			input: "########\n#  **  #\n#@  $ .#\n########",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := LoadLevel(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := l.Deadlocks(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Deadlocks() = %v, want %v", got, tt.want)
+			}
+			if got, want := l.Deadlocked(), len(tt.want) > 0; got != want {
+				t.Errorf("Deadlocked() = %v, want %v", got, want)
+			}
+			if l.Reverse().Deadlocked() {
+				t.Error("Reverse().Deadlocked() = true, want false")
+			}
+		})
+	}
+}
+
+func TestIsDeadSquare(t *testing.T) {
+	l, err := LoadLevel(strings.NewReader("#######\n#     #\n# @$ .#\n#     #\n#######"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		col, row int
+		want     bool
+	}{
+		{1, 1, true},
+		{3, 1, true},
+		{5, 1, true},
+		{3, 2, false},
+		{5, 2, false},
+		{1, 2, true},
+		{0, 0, false},
+		{-1, 0, false},
+	}
+	for _, tt := range tests {
+		if got := l.IsDeadSquare(tt.col, tt.row); got != tt.want {
+			t.Errorf("IsDeadSquare(%d, %d) = %v, want %v", tt.col, tt.row, got, tt.want)
+		}
+	}
+}
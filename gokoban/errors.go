@@ -0,0 +1,126 @@
+package gokoban
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrNoPlayer          = errors.New("level has no player")
+	ErrMultiplePlayers   = errors.New("level has more than one player")
+	ErrNoBoxes           = errors.New("level has no boxes")
+	ErrBoxTargetMismatch = errors.New("number of boxes and targets differ")
+	ErrOpenBorder        = errors.New("level border is open")
+	ErrUnreachableBox    = errors.New("box cannot be reached")
+	ErrUnreachableTarget = errors.New("target cannot be reached")
+	ErrBadSolutionChar   = errors.New("bad solution character")
+	ErrPushMismatch      = errors.New("uppercase solution character does not push a box")
+)
+
+// LevelError wraps one of the Err* values above together with the position
+// it refers to. Col and Row are zero-based and -1 if the error has no position.
+type LevelError struct {
+	Err error
+	Col int
+	Row int
+}
+
+func newLevelError(err error, col, row int) *LevelError {
+	return &LevelError{
+		Err: err,
+		Col: col,
+		Row: row,
+	}
+}
+
+func (e *LevelError) Error() string {
+	if e.Col < 0 || e.Row < 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v at row %d, column %d", e.Err, e.Row+1, e.Col+1)
+}
+
+func (e *LevelError) Unwrap() error {
+	return e.Err
+}
+
+// Problems collects all problems of a level. errors.Is and errors.As match
+// any of them.
+type Problems []*LevelError
+
+func (p Problems) Error() string {
+	ss := make([]string, len(p))
+	for i, e := range p {
+		ss[i] = e.Error()
+	}
+	return strings.Join(ss, "; ")
+}
+
+func (p Problems) Is(target error) bool {
+	for _, e := range p {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Problems) As(target interface{}) bool {
+	for _, e := range p {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectionError is the problem of one level of a collection. Level is the
+// 1-based position of the level in the file or directory, counting levels
+// that were skipped. Skipped is false if only the solution was dropped.
+type CollectionError struct {
+	Level   int
+	Skipped bool
+	Err     error
+}
+
+func (e *CollectionError) Error() string {
+	if !e.Skipped {
+		return fmt.Sprintf("solution %d: %v", e.Level, e.Err)
+	}
+	return fmt.Sprintf("level %d: %v", e.Level, e.Err)
+}
+
+func (e *CollectionError) Unwrap() error {
+	return e.Err
+}
+
+// CollectionErrors collects the problems of all levels of a collection.
+// errors.Is and errors.As match any of them.
+type CollectionErrors []*CollectionError
+
+func (c CollectionErrors) Error() string {
+	ss := make([]string, len(c))
+	for i, e := range c {
+		ss[i] = e.Error()
+	}
+	return strings.Join(ss, "; ")
+}
+
+func (c CollectionErrors) Is(target error) bool {
+	for _, e := range c {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c CollectionErrors) As(target interface{}) bool {
+	for _, e := range c {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,8 +2,6 @@ package gokoban
 
 import (
 	"fmt"
-	"io/ioutil"
-	"sort"
 	"strings"
 )
 
@@ -29,18 +27,36 @@ func (c Course) String() string {
 	}
 }
 
-func parseCourse(b byte) (Course, error) {
+func (c Course) Opposite() Course {
+	switch c {
+	case Up:
+		return Down
+	case Right:
+		return Left
+	case Down:
+		return Up
+	default:
+		return Right
+	}
+}
+
+func (c Course) PushString() string {
+	return strings.ToUpper(c.String())
+}
+
+// parseCourse accepts LURD notation where uppercase letters mark pushes.
+func parseCourse(b byte) (Course, bool, error) {
 	switch b {
-	case 'u':
-		return Up, nil
-	case 'r':
-		return Right, nil
-	case 'd':
-		return Down, nil
-	case 'l':
-		return Left, nil
+	case 'u', 'U':
+		return Up, b == 'U', nil
+	case 'r', 'R':
+		return Right, b == 'R', nil
+	case 'd', 'D':
+		return Down, b == 'D', nil
+	case 'l', 'L':
+		return Left, b == 'L', nil
 	default:
-		return Left, fmt.Errorf("unknown course: %s", string(b))
+		return Left, false, fmt.Errorf("unknown course: %s", string(b))
 	}
 }
 
@@ -70,22 +86,28 @@ func (k fieldKind) isTarget() bool {
 	return k == target || k == boxOnTarget || k == playerOnTarget
 }
 
-type field struct {
-	kind fieldKind
-	curr fieldKind
-	pos  int
+// initial returns what a field of kind k holds before the first move.
+func (k fieldKind) initial() fieldKind {
+	switch k {
+	case boxOnTarget:
+		return box
+	case playerOnTarget:
+		return player
+	default:
+		return k
+	}
 }
 
-func (f *field) currSymbol() string {
-	switch f.curr {
+func (k fieldKind) symbol() string {
+	switch k {
 	case target:
 		return TargetSymbol
 	case boxOnTarget:
-		return BoxSymbol
+		return BoxOnTargetSymbol
 	case box:
 		return BoxSymbol
 	case playerOnTarget:
-		return PlayerSymbol
+		return PlayerOnTargetSymbol
 	case player:
 		return PlayerSymbol
 	case brick:
@@ -95,57 +117,23 @@ func (f *field) currSymbol() string {
 	}
 }
 
-func newField(kind string, pos int) *field {
-	var k fieldKind
-	switch kind {
+func parseFieldKind(symbol string) fieldKind {
+	switch symbol {
 	case TargetSymbol:
-		k = target
+		return target
 	case BoxSymbol:
-		k = box
+		return box
 	case BoxOnTargetSymbol:
-		k = boxOnTarget
+		return boxOnTarget
 	case PlayerOnTargetSymbol:
-		k = playerOnTarget
+		return playerOnTarget
 	case PlayerSymbol:
-		k = player
+		return player
 	case BrickSymbol:
-		k = brick
+		return brick
 	default:
-		k = free
-	}
-	f := &field{
-		kind: k,
-		pos:  pos,
+		return free
 	}
-	f.reset()
-	return f
-}
-
-func (f *field) reset() {
-	switch f.kind {
-	case target:
-		f.curr = target
-	case box:
-		f.curr = box
-	case boxOnTarget:
-		f.curr = box
-	case playerOnTarget:
-		f.curr = player
-	case player:
-		f.curr = player
-	case brick:
-		f.curr = brick
-	default:
-		f.curr = free
-	}
-}
-
-func (f *field) row(width int) int {
-	return f.pos / width
-}
-
-func (f *field) col(width int) int {
-	return f.pos % width
 }
 
 type move struct {
@@ -153,161 +141,73 @@ type move struct {
 	movedBox bool
 }
 
-type Level struct {
-	width    int
-	height   int
-	fields   map[int]map[int]*field
-	pc       int
-	pr       int
-	moves    []*move
-	Solution []Course
-}
-
-func NewLevel(filename, solution string) *Level {
-	bb, err := ioutil.ReadFile(filename)
-	if err != nil {
-		panic(err)
-	}
-
-	lines := strings.Split(string(bb), "\n")
-	maxWidth := 0
-	for i := len(lines) - 1; i >= 0; i-- {
-		line := lines[i]
-		if len(strings.TrimSpace(line)) == 0 {
-			lines = append(lines[:i], lines[i+1:]...)
-			continue
-		}
-		if maxWidth < len(line) {
-			maxWidth = len(line)
-		}
-	}
-
-	level := &Level{
-		width:  maxWidth,
-		height: len(lines),
-		fields: make(map[int]map[int]*field),
-	}
-
-	for r, line := range lines {
-		for c := 0; c < level.width; c++ {
-			kind := " "
-			if c < len(line) {
-				kind = string(line[c])
-			}
-			_, ok := level.fields[c]
-			if !ok {
-				level.fields[c] = make(map[int]*field)
-			}
-			f := newField(kind, level.pos(c, r))
-			level.fields[c][r] = f
-			if f.kind == player || f.kind == playerOnTarget {
-				level.pc = c
-				level.pr = r
-			}
-		}
-	}
-
-	if !level.isValid() {
-		panic(fmt.Errorf("level %q is not valid", filename))
-	}
-
-	bb, err = ioutil.ReadFile(solution)
-	if err != nil {
-		panic(fmt.Errorf("solution %q is not valid: %v", solution, err))
-	}
-	for _, b := range bb {
-		if b == '\r' || b == '\n' {
-			continue
-		}
-		c, err := parseCourse(b)
-		if err != nil {
-			panic(fmt.Errorf("solution %q is not valid: %v", solution, err))
-		}
-		level.Solution = append(level.Solution, c)
+func (m *move) String() string {
+	if m.movedBox {
+		return m.course.PushString()
 	}
+	return m.course.String()
+}
 
-	return level
+// Level keeps the board in flat slices indexed by pos. kinds holds the
+// fields as loaded, curr the current content which is one of brick, free,
+// target, box or player.
+type Level struct {
+	width          int
+	height         int
+	kinds          []fieldKind
+	curr           []fieldKind
+	player         int
+	offTarget      int
+	boxHash        uint64
+	region         int
+	moves          []*move
+	root           *historyNode
+	node           *historyNode
+	nodes          []*historyNode
+	dead           []bool
+	solutionPushes int
+	personalMoves  int
+	personalPushes int
+	reverse        bool
+	start          int
+	Solution       []Course
+	Title          string
+	Author         string
+	Comment        string
 }
 
 func (l *Level) pos(col, row int) int {
 	return row*l.width + col
 }
 
-func (l *Level) isValid() bool {
-	targetCnt := 0
-	boxCnt := 0
-	playerCnt := 0
-
-	for _, rows := range l.fields {
-		for _, f := range rows {
-			switch f.kind {
-			case target:
-				targetCnt++
-			case box:
-				boxCnt++
-			case playerOnTarget:
-				targetCnt++
-				playerCnt++
-			case player:
-				playerCnt++
-			}
-		}
+func (l *Level) index(col, row int) (int, bool) {
+	if col < 0 || row < 0 || col >= l.width || row >= l.height {
+		return 0, false
 	}
+	return l.pos(col, row), true
+}
 
-	if boxCnt == 0 || boxCnt != targetCnt || playerCnt != 1 {
-		return false
+func (l *Level) neighbor(pos int, course Course) (int, bool) {
+	rc, rr := getRelativeMovement(course)
+	return l.index(pos%l.width+rc, pos/l.width+rr)
+}
+
+// floor is what remains of a field once a box or the player left it.
+func (l *Level) floor(pos int) fieldKind {
+	if l.kinds[pos].isTarget() {
+		return target
 	}
+	return free
+}
 
-	for c := 0; c < l.width; c++ {
-		// first non-empty field from above must be a brick
-		for r := 0; r < l.height; r++ {
-			f, ok := l.fields[c][r]
-			if !ok || f.kind == free {
-				continue
-			}
-			if f.kind == brick {
-				break
-			}
-			return false
-		}
-		// first non-empty field from below must be a brick
-		for r := l.height - 1; r >= 0; r-- {
-			f, ok := l.fields[c][r]
-			if !ok || f.kind == free {
-				continue
-			}
-			if f.kind == brick {
-				break
-			}
-			return false
-		}
+func (l *Level) boxMoved(from, to int) {
+	if l.kinds[from].isTarget() {
+		l.offTarget++
 	}
-	for r := 0; r < l.height; r++ {
-		// first non-empty field from left must be a brick
-		for c := 0; c < l.width; c++ {
-			f, ok := l.fields[c][r]
-			if !ok || f.kind == free {
-				continue
-			}
-			if f.kind == brick {
-				break
-			}
-			return false
-		}
-		// first non-empty field from right must be a brick
-		for c := l.width - 1; c >= 0; c-- {
-			f, ok := l.fields[c][r]
-			if !ok || f.kind == free {
-				continue
-			}
-			if f.kind == brick {
-				break
-			}
-			return false
-		}
+	if l.kinds[to].isTarget() {
+		l.offTarget--
 	}
-
-	return true
+	l.boxHash ^= zobrist(from, boxSalt) ^ zobrist(to, boxSalt)
 }
 
 func (l *Level) CanMove(course Course) bool {
@@ -371,19 +271,18 @@ func (l *Level) MoveLeft() {
 }
 
 func (l *Level) isValidMove(course Course) bool {
-	rc, rr := getRelativeMovement(course)
-
-	tc := l.pc + rc
-	tr := l.pr + rr
-
-	to, ok := l.fields[tc][tr]
-	if !ok || to.kind == brick {
+	to, ok := l.neighbor(l.player, course)
+	if !ok || l.kinds[to] == brick {
 		return false
 	}
 
-	if to.curr == box {
-		behindTarget := l.fields[tc+rc][tr+rr]
-		if behindTarget.curr == box || behindTarget.kind == brick {
+	if l.reverse {
+		return l.curr[to] != box
+	}
+
+	if l.curr[to] == box {
+		behindTarget, ok := l.neighbor(to, course)
+		if !ok || l.curr[behindTarget] == box || l.kinds[behindTarget] == brick {
 			return false
 		}
 	}
@@ -392,30 +291,28 @@ func (l *Level) isValidMove(course Course) bool {
 }
 
 func (l *Level) move(course Course) {
-	rc, rr := getRelativeMovement(course)
+	if l.reverse {
+		l.pull(course, false)
+		return
+	}
 
-	tc := l.pc + rc
-	tr := l.pr + rr
-	to := l.fields[tc][tr]
+	to, _ := l.neighbor(l.player, course)
 
-	movedBox := to.curr == box
+	movedBox := l.curr[to] == box
 	if movedBox {
-		l.fields[tc+rc][tr+rr].curr = box
+		behindTarget, _ := l.neighbor(to, course)
+		l.curr[behindTarget] = box
+		l.boxMoved(to, behindTarget)
 	}
 
-	to.curr = player
-
-	from := l.fields[l.pc][l.pr]
-	if from.kind.isTarget() {
-		from.curr = target
-	} else {
-		from.curr = free
+	l.curr[to] = player
+	l.curr[l.player] = l.floor(l.player)
+	l.player = to
+	if movedBox {
+		l.region = l.playerRegion()
 	}
 
-	l.pc += rc
-	l.pr += rr
-
-	l.moves = append(l.moves, &move{
+	l.record(&move{
 		course:   course,
 		movedBox: movedBox,
 	})
@@ -428,65 +325,82 @@ func (l *Level) UndoLastMove() {
 
 	var m *move
 	m, l.moves = l.moves[len(l.moves)-1], l.moves[:len(l.moves)-1]
+	l.node = l.node.parent
+	if l.reverse {
+		l.undoPull(m)
+		return
+	}
 
-	rc, rr := getRelativeMovement(m.course)
-
-	from := l.fields[l.pc][l.pr]
-	behindFrom := l.fields[l.pc+rc][l.pr+rr]
+	from := l.player
+	behindFrom, _ := l.neighbor(from, m.course)
 
-	if behindFrom.curr == box && m.movedBox {
-		from.curr = box
-		if behindFrom.kind.isTarget() {
-			behindFrom.curr = target
-		} else {
-			behindFrom.curr = free
-		}
-	} else if from.kind.isTarget() {
-		from.curr = target
+	if m.movedBox && l.curr[behindFrom] == box {
+		l.curr[from] = box
+		l.curr[behindFrom] = l.floor(behindFrom)
+		l.boxMoved(behindFrom, from)
 	} else {
-		from.curr = free
+		l.curr[from] = l.floor(from)
 	}
 
-	var c Course
-	switch m.course {
-	case Up:
-		c = Down
-	case Right:
-		c = Left
-	case Down:
-		c = Up
-	default:
-		c = Right
+	l.player, _ = l.neighbor(from, m.course.Opposite())
+	l.curr[l.player] = player
+	if m.movedBox {
+		l.region = l.playerRegion()
 	}
+}
 
-	rc, rr = getRelativeMovement(c)
+func (l *Level) Completed() bool {
+	if l.reverse {
+		return l.offTarget == 0 && l.reachable()[l.start]
+	}
+	return l.offTarget == 0
+}
 
-	l.pc += rc
-	l.pr += rr
+func (l *Level) MoveCount() int {
+	return len(l.moves)
+}
 
-	l.fields[l.pc][l.pr].curr = player
+func (l *Level) PushCount() int {
+	n := 0
+	for _, m := range l.moves {
+		if m.movedBox {
+			n++
+		}
+	}
+	return n
 }
 
-func (l *Level) Completed() bool {
-	for _, rows := range l.fields {
-		for _, f := range rows {
-			if f.curr != box && f.kind.isTarget() {
-				return false
+// SetSolution replaces the stored solution. Use it instead of assigning
+// Solution directly so that SolutionPushCount stays up to date.
+func (l *Level) SetSolution(solution []Course) {
+	l.Solution = solution
+	l.solutionPushes = 0
+	l.simulate(func() {
+		for _, c := range solution {
+			if !l.CanMove(c) {
+				return
 			}
+			l.Move(c)
 		}
-	}
+		l.solutionPushes = l.PushCount()
+	})
+}
 
-	return true
+func (l *Level) SolutionPushCount() int {
+	return l.solutionPushes
 }
 
-func (l *Level) MoveCount() int {
-	return len(l.moves)
+// SetPersonalBest makes the HUD show the fewest moves and pushes the player
+// ever solved the level with. Zero moves hide the line.
+func (l *Level) SetPersonalBest(moves, pushes int) {
+	l.personalMoves = moves
+	l.personalPushes = pushes
 }
 
 func (l *Level) Moves() string {
 	s := ""
 	for _, m := range l.moves {
-		s += m.course.String()
+		s += m.String()
 	}
 	return s
 }
@@ -500,56 +414,138 @@ func (l *Level) Height() int {
 }
 
 func (l *Level) PlayerPosition() (int, int) {
-	return l.pc, l.pr
+	return l.player % l.width, l.player / l.width
+}
+
+func (l *Level) InitialPlayerPosition() (int, int) {
+	for pos, k := range l.kinds {
+		if k == player || k == playerOnTarget {
+			return pos % l.width, pos / l.width
+		}
+	}
+	return l.PlayerPosition()
+}
+
+func (l *Level) Symbol(col, row int) string {
+	pos, ok := l.index(col, row)
+	if !ok {
+		return FreeSymbol
+	}
+	return l.symbol(pos)
+}
+
+// symbol combines the current content of pos with its target, since curr
+// only holds box and player.
+func (l *Level) symbol(pos int) string {
+	switch {
+	case l.curr[pos] == box && l.kinds[pos].isTarget():
+		return BoxOnTargetSymbol
+	case l.curr[pos] == player && l.kinds[pos].isTarget():
+		return PlayerOnTargetSymbol
+	default:
+		return l.curr[pos].symbol()
+	}
+}
+
+func (l *Level) BoxCount() int {
+	n := 0
+	for _, k := range l.kinds {
+		if k == box || k == boxOnTarget {
+			n++
+		}
+	}
+	return n
+}
+
+func (l *Level) IsBrick(col, row int) bool {
+	pos, ok := l.index(col, row)
+	return ok && l.kinds[pos] == brick
+}
+
+func (l *Level) IsTarget(col, row int) bool {
+	pos, ok := l.index(col, row)
+	return ok && l.kinds[pos].isTarget()
+}
+
+func (l *Level) IsBox(col, row int) bool {
+	pos, ok := l.index(col, row)
+	return ok && l.curr[pos] == box
+}
+
+func (l *Level) IsInitialBox(col, row int) bool {
+	pos, ok := l.index(col, row)
+	return ok && (l.kinds[pos] == box || l.kinds[pos] == boxOnTarget)
 }
 
 func (l *Level) Reset() {
-	for c, rows := range l.fields {
-		for r, f := range rows {
-			if f.kind == player || f.kind == playerOnTarget {
-				l.pc = c
-				l.pr = r
-			}
-			f.reset()
+	l.offTarget = 0
+	l.boxHash = 0
+	for pos, k := range l.kinds {
+		l.curr[pos] = k.initial()
+		if k == player || k == playerOnTarget {
+			l.player = pos
+		}
+		if k == box {
+			l.offTarget++
+		}
+		if l.curr[pos] == box {
+			l.boxHash ^= zobrist(pos, boxSalt)
 		}
 	}
+	l.region = l.playerRegion()
 	l.moves = l.moves[:0]
+	if l.root == nil {
+		l.resetHistory()
+	}
+	l.node = l.root
+}
+
+// simulate runs f from the initial position and restores the current
+// position and history afterwards.
+func (l *Level) simulate(f func()) {
+	moves := append([]*move(nil), l.moves...)
+	root, nodes := l.root, l.nodes
+	l.resetHistory()
+	l.Reset()
+	f()
+	l.root, l.nodes = root, nodes
+	l.Reset()
+	for _, m := range moves {
+		l.replay(m)
+	}
 }
 
-func (l *Level) PrintSolution(filename string) {
-	if !l.Completed() {
+// replay repeats a recorded move including whether it pulled a box.
+func (l *Level) replay(m *move) {
+	if l.reverse {
+		l.pull(m.course, m.movedBox)
 		return
 	}
-	_ = ioutil.WriteFile(filename, []byte(l.Moves()), 0644)
+	l.move(m.course)
 }
 
 func (l *Level) String() string {
-	var ff []*field
-	for _, rows := range l.fields {
-		for _, f := range rows {
-			ff = append(ff, f)
+	var sb strings.Builder
+	for pos := range l.curr {
+		if pos > 0 && pos%l.width == 0 {
+			sb.WriteString("\n")
 		}
+		sb.WriteString(l.symbol(pos))
 	}
 
-	sort.Slice(ff, func(i, j int) bool {
-		return ff[i].pos < ff[j].pos
-	})
-
-	s := ""
-	for i, f := range ff {
-		if i > 0 && f.col(l.width) == 0 {
-			s += "\n"
-		}
-		s += f.currSymbol()
-	}
+	return fmt.Sprintf("%s\n\n%s\n", sb.String(), l.HUD())
+}
 
-	moves := len(l.moves)
-	currMoves := fmt.Sprintf("curr: %d moves", moves)
-	bestMoves := fmt.Sprintf("best: %d moves", len(l.Solution))
+func (l *Level) HUD() string {
+	currMoves := fmt.Sprintf("curr: %d moves / %d pushes", l.MoveCount(), l.PushCount())
+	bestMoves := fmt.Sprintf("best: %d moves / %d pushes", len(l.Solution), l.SolutionPushCount())
 	ident := (l.width - len(bestMoves)) / 2
-	s += fmt.Sprintf("\n\n%s\n%s\n", Indent(currMoves, ident), Indent(bestMoves, ident))
-
-	return s
+	hud := fmt.Sprintf("%s\n%s", Indent(currMoves, ident), Indent(bestMoves, ident))
+	if l.personalMoves > 0 {
+		personal := fmt.Sprintf("personal best: %d moves / %d pushes", l.personalMoves, l.personalPushes)
+		hud = fmt.Sprintf("%s\n%s", hud, Indent(personal, ident))
+	}
+	return hud
 }
 
 func getRelativeMovement(course Course) (int, int) {
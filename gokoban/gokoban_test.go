@@ -0,0 +1,41 @@
+package gokoban
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSymbol(t *testing.T) {
+	l, err := LoadLevel(strings.NewReader("#########\n#+*  $.$#\n#########"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		col, row int
+		want     string
+	}{
+		{0, 0, BrickSymbol},
+		{1, 1, PlayerOnTargetSymbol},
+		{2, 1, BoxOnTargetSymbol},
+		{3, 1, FreeSymbol},
+		{5, 1, BoxSymbol},
+		{6, 1, TargetSymbol},
+		{-1, 0, FreeSymbol},
+	}
+	for _, tt := range tests {
+		if got := l.Symbol(tt.col, tt.row); got != tt.want {
+			t.Errorf("Symbol(%d, %d) = %q, want %q", tt.col, tt.row, got, tt.want)
+		}
+	}
+
+	l.Move(Right)
+	if got := l.Symbol(1, 1); got != TargetSymbol {
+		t.Errorf("Symbol(1, 1) after moving away = %q, want %q", got, TargetSymbol)
+	}
+	if got := l.Symbol(2, 1); got != PlayerOnTargetSymbol {
+		t.Errorf("Symbol(2, 1) after pushing = %q, want %q", got, PlayerOnTargetSymbol)
+	}
+	if got := l.Symbol(3, 1); got != BoxSymbol {
+		t.Errorf("Symbol(3, 1) after pushing = %q, want %q", got, BoxSymbol)
+	}
+}
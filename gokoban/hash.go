@@ -0,0 +1,70 @@
+package gokoban
+
+const (
+	boxSalt    uint64 = 0x9e3779b97f4a7c15
+	playerSalt uint64 = 0xc2b2ae3d27d4eb4f
+)
+
+// zobrist derives the key of a position with splitmix64, so that levels of
+// the same size share their keys without a precomputed table.
+func zobrist(pos int, salt uint64) uint64 {
+	z := uint64(pos)*0xbf58476d1ce4e5b9 + salt
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Hash identifies the current position by its boxes and the region the
+// player can walk to, so positions that only differ by where the player
+// stands inside the same region hash alike.
+func (l *Level) Hash() uint64 {
+	return l.boxHash ^ zobrist(l.region, playerSalt)
+}
+
+// Equal reports whether both levels share the same bricks and targets and
+// are in equivalent positions in the sense of Hash.
+func (l *Level) Equal(other *Level) bool {
+	if l.width != other.width || l.height != other.height {
+		return false
+	}
+	if l.boxHash != other.boxHash || l.region != other.region {
+		return false
+	}
+	for pos := range l.kinds {
+		if (l.kinds[pos] == brick) != (other.kinds[pos] == brick) {
+			return false
+		}
+		if l.kinds[pos].isTarget() != other.kinds[pos].isTarget() {
+			return false
+		}
+		if (l.curr[pos] == box) != (other.curr[pos] == box) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Level) Clone() *Level {
+	clone := *l
+	clone.kinds = append([]fieldKind(nil), l.kinds...)
+	clone.curr = append([]fieldKind(nil), l.curr...)
+	clone.moves = append([]*move(nil), l.moves...)
+	clone.dead = append([]bool(nil), l.dead...)
+	clone.Solution = append([]Course(nil), l.Solution...)
+	clone.copyHistory(l, func(c Course) Course {
+		return c
+	})
+	clone.node = clone.nodes[l.node.id]
+	return &clone
+}
+
+// playerRegion returns the smallest pos the player can walk to without
+// pushing a box.
+func (l *Level) playerRegion() int {
+	for pos, ok := range l.reachable() {
+		if ok {
+			return pos
+		}
+	}
+	return l.player
+}
@@ -0,0 +1,72 @@
+package gokoban
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	room := "########\n#      #\n# $  $ #\n#  @   #\n#.    .#\n########"
+	tests := []struct {
+		name   string
+		a, b   string
+		movesA string
+		movesB string
+		// Hash ignores bricks and targets, Equal does not.
+		wantHash  bool
+		wantEqual bool
+	}{
+		{name: "same position", a: room, b: room, wantHash: true, wantEqual: true},
+		{name: "player walked inside region", a: room, b: room, movesB: "rrdll", wantHash: true, wantEqual: true},
+		{name: "pushed box", a: room, b: room, movesB: "lu", wantHash: false, wantEqual: false},
+		{name: "transposed pushes", a: room, b: room, movesA: "ludrrru", movesB: "rrudlllu", wantHash: true, wantEqual: true},
+		{name: "other region", a: "#######\n#@ $ .#\n#######", b: "#######\n#  $@.#\n#######", wantHash: false, wantEqual: false},
+		{name: "other targets", a: "#######\n#@ $ .#\n#######", b: "#######\n#@ $. #\n#######", wantHash: true, wantEqual: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := play(t, tt.a, tt.movesA)
+			b := play(t, tt.b, tt.movesB)
+			if got := a.Hash() == b.Hash(); got != tt.wantHash {
+				t.Errorf("Hash() equal = %v, want %v", got, tt.wantHash)
+			}
+			if got := a.Equal(b); got != tt.wantEqual {
+				t.Errorf("Equal() = %v, want %v", got, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	l := play(t, "#######\n#@ $ .#\n#######", "r")
+	clone := l.Clone()
+	clone.Move(Right)
+	if l.Equal(clone) {
+		t.Error("moving the clone changed the original")
+	}
+	clone.UndoLastMove()
+	if !l.Equal(clone) || l.Hash() != clone.Hash() {
+		t.Errorf("clone after undo = %v, want %v", clone, l)
+	}
+}
+
+// play loads input and plays moves in LURD notation, failing on the first
+// move that cannot be made.
+func play(t *testing.T, input, moves string) *Level {
+	t.Helper()
+	l, err := LoadLevel(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	courses, err := ParseLURD(moves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range courses {
+		if !l.CanMove(c) {
+			t.Fatalf("move %d of %q cannot be made:\n%v", i, moves, l)
+		}
+		l.Move(c)
+	}
+	return l
+}
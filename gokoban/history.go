@@ -0,0 +1,158 @@
+package gokoban
+
+// historyNode is a position in the move history. Undoing a move keeps it as
+// a child of the previous position, so new moves start another branch.
+type historyNode struct {
+	id       int
+	move     *move
+	parent   *historyNode
+	children []*historyNode
+	selected int
+}
+
+type Branch struct {
+	Course Course
+	Push   bool
+	// Depth is the number of moves Redo can repeat along the branch.
+	Depth int
+}
+
+// record appends m to the current line and the history tree.
+func (l *Level) record(m *move) {
+	l.moves = append(l.moves, m)
+	for i, child := range l.node.children {
+		if *child.move == *m {
+			l.node.selected = i
+			l.node = child
+			return
+		}
+	}
+	child := &historyNode{
+		id:     len(l.nodes),
+		move:   m,
+		parent: l.node,
+	}
+	l.nodes = append(l.nodes, child)
+	l.node.children = append(l.node.children, child)
+	l.node.selected = len(l.node.children) - 1
+	l.node = child
+}
+
+func (l *Level) resetHistory() {
+	l.root = &historyNode{}
+	l.nodes = []*historyNode{l.root}
+	l.node = l.root
+}
+
+// copyHistory rebuilds the history tree of other with every course mapped
+// by course and moves to its root.
+func (l *Level) copyHistory(other *Level, course func(Course) Course) {
+	l.nodes = make([]*historyNode, len(other.nodes))
+	for i, n := range other.nodes {
+		l.nodes[i] = &historyNode{
+			id:       n.id,
+			selected: n.selected,
+		}
+		if n.move != nil {
+			l.nodes[i].move = &move{
+				course:   course(n.move.course),
+				movedBox: n.move.movedBox,
+			}
+		}
+	}
+	for i, n := range other.nodes {
+		if n.parent != nil {
+			l.nodes[i].parent = l.nodes[n.parent.id]
+		}
+		for _, child := range n.children {
+			l.nodes[i].children = append(l.nodes[i].children, l.nodes[child.id])
+		}
+	}
+	l.root = l.nodes[0]
+	l.node = l.root
+}
+
+func (l *Level) CanRedo() bool {
+	return len(l.node.children) > 0
+}
+
+// Redo repeats the move of the selected branch at the current position.
+func (l *Level) Redo() bool {
+	if !l.CanRedo() {
+		return false
+	}
+	l.replay(l.node.children[l.node.selected].move)
+	return true
+}
+
+// Branches lists the moves that were made from the current position.
+func (l *Level) Branches() []Branch {
+	branches := make([]Branch, len(l.node.children))
+	for i, child := range l.node.children {
+		depth := 1
+		for n := child; len(n.children) > 0; n = n.children[n.selected] {
+			depth++
+		}
+		branches[i] = Branch{
+			Course: child.move.course,
+			Push:   child.move.movedBox,
+			Depth:  depth,
+		}
+	}
+	return branches
+}
+
+// SelectedBranch returns the index of the branch Redo follows.
+func (l *Level) SelectedBranch() int {
+	return l.node.selected
+}
+
+func (l *Level) SelectBranch(i int) bool {
+	if i < 0 || i >= len(l.node.children) {
+		return false
+	}
+	l.node.selected = i
+	return true
+}
+
+// HistoryLines returns the moves from the initial position to every end
+// of the history tree.
+func (l *Level) HistoryLines() [][]Course {
+	var lines [][]Course
+	for _, n := range l.nodes {
+		if len(n.children) > 0 || n.move == nil {
+			continue
+		}
+		var line []Course
+		for ; n.move != nil; n = n.parent {
+			line = append([]Course{n.move.course}, line...)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// CurrentNode identifies the current position in the history tree.
+func (l *Level) CurrentNode() int {
+	return l.node.id
+}
+
+func (l *Level) NodeCount() int {
+	return len(l.nodes)
+}
+
+// JumpTo moves to any position of the history tree.
+func (l *Level) JumpTo(node int) bool {
+	if node < 0 || node >= len(l.nodes) {
+		return false
+	}
+	var line []*move
+	for n := l.nodes[node]; n.move != nil; n = n.parent {
+		line = append([]*move{n.move}, line...)
+	}
+	l.Reset()
+	for _, m := range line {
+		l.replay(m)
+	}
+	return true
+}
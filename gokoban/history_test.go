@@ -0,0 +1,104 @@
+package gokoban
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHistoryTree(t *testing.T) {
+	l := play(t, "#######\n#     #\n# @$ .#\n#     #\n#######", "ur")
+	l.UndoLastMove()
+	l.UndoLastMove()
+	l.Move(Down)
+
+	if got, want := l.NodeCount(), 4; got != want {
+		t.Errorf("NodeCount() = %d, want %d", got, want)
+	}
+	if got, want := l.HistoryLines(), [][]Course{{Up, Right}, {Down}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("HistoryLines() = %v, want %v", got, want)
+	}
+
+	l.UndoLastMove()
+	if got, want := l.Branches(), []Branch{{Course: Up, Depth: 2}, {Course: Down, Depth: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Branches() = %v, want %v", got, want)
+	}
+	if got, want := l.SelectedBranch(), 1; got != want {
+		t.Errorf("SelectedBranch() = %d, want %d", got, want)
+	}
+
+	tests := []struct {
+		name   string
+		branch int
+		ok     bool
+		moves  string
+	}{
+		{name: "first branch", branch: 0, ok: true, moves: "ur"},
+		{name: "second branch", branch: 1, ok: true, moves: "d"},
+		{name: "out of range", branch: 2, ok: false, moves: "d"},
+		{name: "negative", branch: -1, ok: false, moves: "d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for l.MoveCount() > 0 {
+				l.UndoLastMove()
+			}
+			if got := l.SelectBranch(tt.branch); got != tt.ok {
+				t.Errorf("SelectBranch(%d) = %v, want %v", tt.branch, got, tt.ok)
+			}
+			for l.Redo() {
+			}
+			if got := l.Moves(); got != tt.moves {
+				t.Errorf("Moves() after redoing = %q, want %q", got, tt.moves)
+			}
+		})
+	}
+	if got, want := l.NodeCount(), 4; got != want {
+		t.Errorf("NodeCount() after redoing = %d, want %d", got, want)
+	}
+}
+
+func TestHistoryRepeatedMove(t *testing.T) {
+	l := play(t, "#######\n#     #\n# @$ .#\n#     #\n#######", "u")
+	l.UndoLastMove()
+	l.Move(Up)
+	if got, want := l.NodeCount(), 2; got != want {
+		t.Errorf("NodeCount() = %d, want %d", got, want)
+	}
+	if l.CanRedo() {
+		t.Error("CanRedo() = true at the end of the line, want false")
+	}
+}
+
+func TestJumpTo(t *testing.T) {
+	l := play(t, "#######\n#     #\n# @$ .#\n#     #\n#######", "ur")
+	l.UndoLastMove()
+	l.UndoLastMove()
+	l.Move(Right)
+
+	tests := []struct {
+		node  int
+		ok    bool
+		moves string
+	}{
+		{node: 0, ok: true, moves: ""},
+		{node: 2, ok: true, moves: "ur"},
+		{node: 3, ok: true, moves: "R"},
+		{node: 1, ok: true, moves: "u"},
+		{node: 4, ok: false, moves: "u"},
+		{node: -1, ok: false, moves: "u"},
+	}
+	for _, tt := range tests {
+		if got := l.JumpTo(tt.node); got != tt.ok {
+			t.Errorf("JumpTo(%d) = %v, want %v", tt.node, got, tt.ok)
+		}
+		if got := l.Moves(); got != tt.moves {
+			t.Errorf("Moves() after JumpTo(%d) = %q, want %q", tt.node, got, tt.moves)
+		}
+		if tt.ok && l.CurrentNode() != tt.node {
+			t.Errorf("CurrentNode() after JumpTo(%d) = %d", tt.node, l.CurrentNode())
+		}
+	}
+	if got, want := l.NodeCount(), 4; got != want {
+		t.Errorf("NodeCount() = %d, want %d", got, want)
+	}
+}
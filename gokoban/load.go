@@ -0,0 +1,144 @@
+package gokoban
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+func NewLevel(filename, solution string) *Level {
+	f, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	level, err := LoadLevel(f)
+	if err != nil {
+		panic(fmt.Errorf("level %q is not valid: %v", filename, err))
+	}
+
+	s, err := os.Open(solution)
+	if err != nil {
+		panic(fmt.Errorf("solution %q is not valid: %v", solution, err))
+	}
+	defer s.Close()
+
+	if err := level.LoadSolution(s); err != nil {
+		panic(fmt.Errorf("solution %q is not valid: %v", solution, err))
+	}
+
+	return level
+}
+
+func LoadLevelFS(fsys fs.FS, name string) (*Level, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadLevel(f)
+}
+
+func LoadLevel(r io.Reader) (*Level, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return parseLevel(lines)
+}
+
+func parseLevel(lines []string) (*Level, error) {
+	maxWidth := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimRight(lines[i], "\r")
+		if len(strings.TrimSpace(line)) == 0 {
+			lines = append(lines[:i], lines[i+1:]...)
+			continue
+		}
+		lines[i] = line
+		if maxWidth < len(line) {
+			maxWidth = len(line)
+		}
+	}
+
+	level := &Level{
+		width:  maxWidth,
+		height: len(lines),
+		kinds:  make([]fieldKind, maxWidth*len(lines)),
+		curr:   make([]fieldKind, maxWidth*len(lines)),
+	}
+
+	for r, line := range lines {
+		for c := 0; c < level.width; c++ {
+			kind := FreeSymbol
+			if c < len(line) {
+				kind = string(line[c])
+			}
+			level.kinds[level.pos(c, r)] = parseFieldKind(kind)
+		}
+	}
+	// Validate only looks at the fields as loaded, Reset needs a player.
+	if problems := level.Validate(); len(problems) > 0 {
+		return nil, problems
+	}
+	level.Reset()
+	level.dead = level.deadSquares()
+
+	return level, nil
+}
+
+func (l *Level) LoadSolutionFS(fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return l.LoadSolution(f)
+}
+
+func (l *Level) LoadSolution(r io.Reader) error {
+	var steps []lurdStep
+	row := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		for col := 0; col < len(line); col++ {
+			c, push, err := parseCourse(line[col])
+			if err != nil {
+				return newLevelError(ErrBadSolutionChar, col, row)
+			}
+			steps = append(steps, lurdStep{
+				course: c,
+				push:   push,
+				col:    col,
+				row:    row,
+			})
+		}
+		row++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := l.checkPushes(steps); err != nil {
+		return err
+	}
+
+	solution := make([]Course, len(steps))
+	for i, step := range steps {
+		solution[i] = step.course
+	}
+	l.SetSolution(solution)
+	return nil
+}
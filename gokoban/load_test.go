@@ -0,0 +1,103 @@
+package gokoban
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadLevelWithoutPlayer(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty", input: ""},
+		{name: "blank lines", input: "\n  \n\t\n"},
+		{name: "all floor", input: "-----\n-----\n-----"},
+		{name: "no player", input: "#####\n#$. #\n#####"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := LoadLevel(strings.NewReader(tt.input))
+			if l != nil {
+				t.Errorf("LoadLevel() = %v, want nil", l)
+			}
+			if !errors.Is(err, ErrNoPlayer) {
+				t.Errorf("LoadLevel() error = %v, want %v", err, ErrNoPlayer)
+			}
+		})
+	}
+}
+
+func TestLoadLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		width, height int
+		want          string
+	}{
+		{name: "plain", input: "#####\n#@$.#\n#####", width: 5, height: 3, want: "#####\n#@$.#\n#####"},
+		{name: "windows line endings", input: "#####\r\n#@$.#\r\n#####\r\n", width: 5, height: 3, want: "#####\n#@$.#\n#####"},
+		{name: "blank lines around", input: "\n\n#####\n#@$.#\n#####\n\n", width: 5, height: 3, want: "#####\n#@$.#\n#####"},
+		{name: "ragged lines", input: "####\n#@$.#\n#####", width: 5, height: 3, want: "#### \n#@$.#\n#####"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := LoadLevel(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Width() != tt.width || l.Height() != tt.height {
+				t.Errorf("LoadLevel() = %dx%d, want %dx%d", l.Width(), l.Height(), tt.width, tt.height)
+			}
+			var rows []string
+			for r := 0; r < l.Height(); r++ {
+				var row strings.Builder
+				for c := 0; c < l.Width(); c++ {
+					row.WriteString(l.Symbol(c, r))
+				}
+				rows = append(rows, row.String())
+			}
+			if got := strings.Join(rows, "\n"); got != tt.want {
+				t.Errorf("LoadLevel() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadLevelFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"level.txt":    {Data: []byte("#####\n#@$.#\n#####\n")},
+		"invalid.txt":  {Data: []byte("#####\n#@@.#\n#####\n")},
+		"solution.txt": {Data: []byte("R\n")},
+		"bad.txt":      {Data: []byte("Rx\n")},
+	}
+	tests := []struct {
+		name     string
+		level    string
+		solution string
+		want     error
+	}{
+		{name: "level and solution", level: "level.txt", solution: "solution.txt"},
+		{name: "missing level", level: "missing.txt", want: fs.ErrNotExist},
+		{name: "invalid level", level: "invalid.txt", want: ErrMultiplePlayers},
+		{name: "missing solution", level: "level.txt", solution: "missing.txt", want: fs.ErrNotExist},
+		{name: "bad solution", level: "level.txt", solution: "bad.txt", want: ErrBadSolutionChar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := LoadLevelFS(fsys, tt.level)
+			if err == nil && tt.solution != "" {
+				err = l.LoadSolutionFS(fsys, tt.solution)
+			}
+			if !errors.Is(err, tt.want) && !(err == nil && tt.want == nil) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			if err == nil && len(l.Solution) != 1 {
+				t.Errorf("solution = %v, want one move", l.Solution)
+			}
+		})
+	}
+}
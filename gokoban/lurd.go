@@ -0,0 +1,96 @@
+package gokoban
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+type lurdStep struct {
+	course Course
+	push   bool
+	col    int
+	row    int
+}
+
+// checkPushes replays steps from the initial position and reports the first
+// uppercase step that does not push a box. Checking stops at the first
+// step that cannot be played at all.
+func (l *Level) checkPushes(steps []lurdStep) error {
+	var err error
+	l.simulate(func() {
+		for _, step := range steps {
+			if !l.CanMove(step.course) {
+				return
+			}
+			l.Move(step.course)
+			if step.push && !l.moves[len(l.moves)-1].movedBox {
+				err = newLevelError(ErrPushMismatch, step.col, step.row)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// FormatLURD renders courses played from the initial position in LURD
+// notation with pushes in uppercase. Courses that cannot be played are
+// written in lowercase.
+func (l *Level) FormatLURD(courses []Course) string {
+	var sb strings.Builder
+	l.simulate(func() {
+		playable := true
+		for _, c := range courses {
+			playable = playable && l.CanMove(c)
+			if !playable {
+				sb.WriteString(c.String())
+				continue
+			}
+			l.Move(c)
+			sb.WriteString(l.moves[len(l.moves)-1].String())
+		}
+	})
+	return sb.String()
+}
+
+// ParseLURD reads courses in LURD notation. Whether a step is marked as
+// push is ignored.
+func ParseLURD(s string) ([]Course, error) {
+	courses := make([]Course, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c, _, err := parseCourse(s[i])
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, c)
+	}
+	return courses, nil
+}
+
+func (l *Level) WriteSolution(w io.Writer) error {
+	_, err := io.WriteString(w, l.FormatLURD(l.Solution))
+	return err
+}
+
+// PrintSolution writes the moves of a completed level to filename. Errors
+// are ignored.
+//
+// Deprecated: Use WriteSolution, which reports errors.
+func (l *Level) PrintSolution(filename string) {
+	if !l.Completed() {
+		return
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	moves := make([]Course, 0, len(l.moves))
+	for _, m := range l.moves {
+		moves = append(moves, m.course)
+	}
+	solved := l.Clone()
+	solved.SetSolution(moves)
+	_ = solved.WriteSolution(f)
+}
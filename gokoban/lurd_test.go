@@ -0,0 +1,110 @@
+package gokoban
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLURDRoundTrip(t *testing.T) {
+	l, err := LoadLevel(strings.NewReader("#######\n#@ $ .#\n#######"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "pushes uppercase", input: "rRR", want: "rRR"},
+		{name: "pushes marked by position", input: "rrr", want: "rRR"},
+		{name: "walking back", input: "rRRlL", want: "rRRll"},
+		{name: "unplayable rest lowercase", input: "lRR", want: "lrr"},
+		{name: "empty", input: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			courses, err := ParseLURD(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := l.FormatLURD(courses); got != tt.want {
+				t.Errorf("FormatLURD(ParseLURD(%q)) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLURDError(t *testing.T) {
+	if _, err := ParseLURD("rRx"); err == nil {
+		t.Error("ParseLURD() error = nil, want an error")
+	}
+}
+
+func TestWriteSolution(t *testing.T) {
+	l, err := LoadLevel(strings.NewReader("#######\n#@ $ .#\n#######"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.LoadSolution(strings.NewReader("r\nR\nR\n")); err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := l.WriteSolution(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sb.String(), "rRR"; got != want {
+		t.Errorf("WriteSolution() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintSolution(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "solution.txt")
+	l := play(t, "#######\n#@ $ .#\n#######", "r")
+	l.PrintSolution(file)
+	if _, err := ioutil.ReadFile(file); err == nil {
+		t.Error("PrintSolution() wrote an incomplete level")
+	}
+
+	l = play(t, "#######\n#@ $ .#\n#######", "rlrRR")
+	l.PrintSolution(file)
+	if bb, err := ioutil.ReadFile(file); err != nil || string(bb) != "rlrRR" {
+		t.Errorf("PrintSolution() wrote %q, %v, want %q", bb, err, "rlrRR")
+	}
+	if len(l.Solution) != 0 {
+		t.Errorf("PrintSolution() changed the solution to %q", l.FormatLURD(l.Solution))
+	}
+}
+
+func TestLoadSolutionErrors(t *testing.T) {
+	level := "#######\n#@  $.#\n#######"
+	tests := []struct {
+		name     string
+		solution string
+		want     error
+		col, row int
+	}{
+		{name: "bad character", solution: "rx", want: ErrBadSolutionChar, col: 1, row: 0},
+		{name: "bad character on second line", solution: "rr\nrx", want: ErrBadSolutionChar, col: 1, row: 1},
+		{name: "uppercase without push", solution: "R", want: ErrPushMismatch, col: 0, row: 0},
+		{name: "uppercase without push on second line", solution: "r\nR", want: ErrPushMismatch, col: 0, row: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := LoadLevel(strings.NewReader(level))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = l.LoadSolution(strings.NewReader(tt.solution))
+			var le *LevelError
+			if !errors.As(err, &le) {
+				t.Fatalf("LoadSolution() error = %v, want a *LevelError", err)
+			}
+			if le.Err != tt.want || le.Col != tt.col || le.Row != tt.row {
+				t.Errorf("LoadSolution() error = %v at %d, %d, want %v at %d, %d", le.Err, le.Col, le.Row, tt.want, tt.col, tt.row)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package gokoban
+
+// reachable marks all fields the player can walk to without pushing a box.
+func (l *Level) reachable() []bool {
+	visited := make([]bool, len(l.curr))
+	visited[l.player] = true
+	queue := []int{l.player}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		for _, course := range []Course{Up, Right, Down, Left} {
+			to, ok := l.neighbor(pos, course)
+			if !ok || visited[to] || l.kinds[to] == brick || l.curr[to] == box {
+				continue
+			}
+			visited[to] = true
+			queue = append(queue, to)
+		}
+	}
+	return visited
+}
+
+// walk returns the shortest way from one field to another that does not
+// touch any box.
+func (l *Level) walk(from, to int) ([]Course, bool) {
+	prev := make([]int, len(l.curr))
+	for pos := range prev {
+		prev[pos] = -1
+	}
+	prev[from] = from
+	queue := []int{from}
+	for len(queue) > 0 && prev[to] < 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		for _, course := range []Course{Up, Right, Down, Left} {
+			next, ok := l.neighbor(pos, course)
+			if !ok || prev[next] >= 0 || l.kinds[next] == brick || l.curr[next] == box {
+				continue
+			}
+			prev[next] = pos
+			queue = append(queue, next)
+		}
+	}
+	if prev[to] < 0 {
+		return nil, false
+	}
+
+	var path []Course
+	for pos := to; pos != from; pos = prev[pos] {
+		for _, course := range []Course{Up, Right, Down, Left} {
+			if next, ok := l.neighbor(prev[pos], course); ok && next == pos {
+				path = append(path, course)
+				break
+			}
+		}
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, true
+}
+
+// PathTo returns the shortest walk of the player to the given field that
+// does not push any box.
+func (l *Level) PathTo(col, row int) ([]Course, bool) {
+	pos, ok := l.index(col, row)
+	if !ok || l.kinds[pos] == brick || l.curr[pos] == box {
+		return nil, false
+	}
+	return l.walk(l.player, pos)
+}
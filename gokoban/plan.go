@@ -0,0 +1,106 @@
+package gokoban
+
+type pushState struct {
+	box    int
+	course Course
+	parent *pushState
+}
+
+// PlanBoxPush finds the moves that bring the box at from to the field to
+// with as few pushes as possible. All other boxes stay where they are.
+func (l *Level) PlanBoxPush(from, to Position) ([]Course, bool) {
+	start, ok := l.index(from.Col, from.Row)
+	if !ok || l.reverse || l.curr[start] != box {
+		return nil, false
+	}
+	goal, ok := l.index(to.Col, to.Row)
+	if !ok || l.kinds[goal] == brick || (l.curr[goal] == box && goal != start) {
+		return nil, false
+	}
+	if start == goal {
+		return []Course{}, true
+	}
+
+	work := l.Clone()
+	work.curr[start] = work.floor(start)
+	free := func(pos, boxPos int) bool {
+		return pos != boxPos && work.kinds[pos] != brick && work.curr[pos] != box
+	}
+
+	visited := make(map[int]bool)
+	queue := []*pushState{nil}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		boxPos, player := start, l.player
+		if s != nil {
+			boxPos = s.box
+			player, _ = l.neighbor(s.box, s.course.Opposite())
+		}
+		reach := work.reachableFrom(player, boxPos)
+
+		for _, course := range []Course{Up, Right, Down, Left} {
+			stand, ok := l.neighbor(boxPos, course.Opposite())
+			if !ok || !reach[stand] {
+				continue
+			}
+			next, ok := l.neighbor(boxPos, course)
+			if !ok || !free(next, boxPos) || visited[next*4+int(course)] {
+				continue
+			}
+			visited[next*4+int(course)] = true
+			pushed := &pushState{box: next, course: course, parent: s}
+			if next == goal {
+				return l.pushPath(pushed), true
+			}
+			queue = append(queue, pushed)
+		}
+	}
+
+	return nil, false
+}
+
+// reachableFrom marks all fields the player can walk to from pos while the
+// box that is planned for stands at boxPos.
+func (l *Level) reachableFrom(pos, boxPos int) []bool {
+	visited := make([]bool, len(l.curr))
+	visited[pos] = true
+	queue := []int{pos}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, course := range []Course{Up, Right, Down, Left} {
+			to, ok := l.neighbor(p, course)
+			if !ok || visited[to] || to == boxPos || l.kinds[to] == brick || l.curr[to] == box {
+				continue
+			}
+			visited[to] = true
+			queue = append(queue, to)
+		}
+	}
+	return visited
+}
+
+// pushPath replays the pushes on a copy of the level and fills in the walks
+// between them.
+func (l *Level) pushPath(last *pushState) []Course {
+	var pushes []*pushState
+	for s := last; s != nil; s = s.parent {
+		pushes = append([]*pushState{s}, pushes...)
+	}
+
+	work := l.Clone()
+	var path []Course
+	for _, s := range pushes {
+		boxPos, _ := l.neighbor(s.box, s.course.Opposite())
+		stand, _ := l.neighbor(boxPos, s.course.Opposite())
+		walk, _ := work.walk(work.player, stand)
+		for _, course := range walk {
+			work.move(course)
+		}
+		work.move(s.course)
+		path = append(append(path, walk...), s.course)
+	}
+	return path
+}
@@ -0,0 +1,185 @@
+package gokoban
+
+import (
+	"errors"
+)
+
+var ErrNotReverse = errors.New("level is not in reverse mode")
+
+// Reverse returns the level in reverse mode: all boxes start on the targets
+// and the player pulls instead of pushes. Move only walks, Pull drags the
+// box behind the player along. The level is completed once every
+// box is back on its initial field and the player can walk to its initial
+// field.
+func (l *Level) Reverse() *Level {
+	r := &Level{
+		width:   l.width,
+		height:  l.height,
+		kinds:   make([]fieldKind, len(l.kinds)),
+		curr:    make([]fieldKind, len(l.curr)),
+		reverse: true,
+		Title:   l.Title,
+		Author:  l.Author,
+		Comment: l.Comment,
+	}
+	pc, pr := l.InitialPlayerPosition()
+	r.start = l.pos(pc, pr)
+
+	for pos, k := range l.kinds {
+		switch k {
+		case box:
+			r.kinds[pos] = target
+		case target, playerOnTarget:
+			r.kinds[pos] = box
+		case player:
+			r.kinds[pos] = free
+		default:
+			r.kinds[pos] = k
+		}
+	}
+
+	placed := r.start
+	if r.kinds[placed] != free {
+		inside := l.inside()
+		for pos, k := range r.kinds {
+			if inside[pos] && (k == free || k == target) {
+				placed = pos
+				break
+			}
+		}
+	}
+	if r.kinds[placed] == target {
+		r.kinds[placed] = playerOnTarget
+	} else {
+		r.kinds[placed] = player
+	}
+
+	r.Reset()
+	r.dead = r.deadSquares()
+	return r
+}
+
+func (l *Level) Reversed() bool {
+	return l.reverse
+}
+
+// PlacePlayer moves the player of a reverse level to any free field inside
+// the level. It is only possible before the first move and drops the
+// history, which does not fit the new start.
+func (l *Level) PlacePlayer(col, row int) bool {
+	pos, ok := l.index(col, row)
+	if !ok || !l.reverse || len(l.moves) > 0 {
+		return false
+	}
+	if l.kinds[pos] == brick || l.curr[pos] == box || !l.inside()[pos] {
+		return false
+	}
+
+	for p, k := range l.kinds {
+		switch k {
+		case player:
+			l.kinds[p] = free
+		case playerOnTarget:
+			l.kinds[p] = target
+		}
+	}
+	if l.kinds[pos] == target {
+		l.kinds[pos] = playerOnTarget
+	} else {
+		l.kinds[pos] = player
+	}
+	l.resetHistory()
+	l.Reset()
+	return true
+}
+
+func (l *Level) CanPull(course Course) bool {
+	if !l.reverse || !l.isValidMove(course) {
+		return false
+	}
+	behind, ok := l.neighbor(l.player, course.Opposite())
+	return ok && l.curr[behind] == box
+}
+
+func (l *Level) Pull(course Course) {
+	l.pull(course, true)
+}
+
+// pull moves the player and, if grab is set, drags a box that is directly
+// behind it.
+func (l *Level) pull(course Course, grab bool) {
+	from := l.player
+	to, _ := l.neighbor(from, course)
+	behind, ok := l.neighbor(from, course.Opposite())
+	pulled := grab && ok && l.curr[behind] == box
+
+	l.curr[from] = l.floor(from)
+	if pulled {
+		l.curr[behind] = l.floor(behind)
+		l.curr[from] = box
+		l.boxMoved(behind, from)
+	}
+	l.curr[to] = player
+	l.player = to
+	if pulled {
+		l.region = l.playerRegion()
+	}
+
+	l.record(&move{
+		course:   course,
+		movedBox: pulled,
+	})
+}
+
+func (l *Level) undoPull(m *move) {
+	from := l.player
+	back, _ := l.neighbor(from, m.course.Opposite())
+
+	l.curr[from] = l.floor(from)
+	if m.movedBox {
+		behind, _ := l.neighbor(back, m.course.Opposite())
+		l.curr[behind] = box
+		l.boxMoved(back, behind)
+	}
+	l.curr[back] = player
+	l.player = back
+	if m.movedBox {
+		l.region = l.playerRegion()
+	}
+}
+
+// ForwardSolution turns a completed reverse game into a solution of the
+// forward level: walk from the initial player field to where the last pull
+// ended, then take back every reverse move down to the first pull. The
+// walks around the pulls are not needed in the forward direction.
+func (l *Level) ForwardSolution() ([]Course, error) {
+	if !l.reverse {
+		return nil, ErrNotReverse
+	}
+	if !l.Completed() {
+		return nil, ErrNotCompleted
+	}
+
+	first, last := -1, -1
+	for i, m := range l.moves {
+		if m.movedBox {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if last < 0 {
+		return []Course{}, nil
+	}
+
+	end := l.player
+	for i := len(l.moves) - 1; i > last; i-- {
+		end, _ = l.neighbor(end, l.moves[i].course.Opposite())
+	}
+	solution, _ := l.walk(l.start, end)
+	for i := last; i >= first; i-- {
+		solution = append(solution, l.moves[i].course.Opposite())
+	}
+	return solution, nil
+}
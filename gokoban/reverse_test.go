@@ -0,0 +1,95 @@
+package gokoban
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestForwardSolution(t *testing.T) {
+	l, err := LoadLevel(strings.NewReader("#######\n#@ $ .#\n#######"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := l.Reverse()
+	for _, c := range []Course{Right, Right, Right} {
+		r.Move(c)
+	}
+	r.Pull(Left)
+	r.Pull(Left)
+	r.Move(Left)
+	r.Move(Right)
+	if !r.Completed() {
+		t.Fatalf("reverse game is not completed:\n%v", r)
+	}
+
+	solution, err := r.ForwardSolution()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := l.FormatLURD(solution), "rRR"; got != want {
+		t.Errorf("ForwardSolution() = %q, want %q", got, want)
+	}
+	if v := l.VerifySolution(solution); !v.Valid {
+		t.Errorf("ForwardSolution() fails at step %d: %v", v.Step, v.Err)
+	}
+}
+
+func TestForwardSolutionNotReverse(t *testing.T) {
+	l, err := LoadLevel(strings.NewReader("#####\n#@$.#\n#####"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.ForwardSolution(); err != ErrNotReverse {
+		t.Errorf("ForwardSolution() error = %v, want %v", err, ErrNotReverse)
+	}
+}
+
+func TestForwardSolutionNotCompleted(t *testing.T) {
+	l, err := LoadLevel(strings.NewReader("#######\n#@ $ .#\n#######"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := l.Reverse()
+	r.Move(Right)
+	if _, err := r.ForwardSolution(); err != ErrNotCompleted {
+		t.Errorf("ForwardSolution() error = %v, want %v", err, ErrNotCompleted)
+	}
+}
+
+func TestPlacePlayer(t *testing.T) {
+	tests := []struct {
+		name     string
+		col, row int
+		moves    []Course
+		forward  bool
+		want     bool
+	}{
+		{name: "free field", col: 4, row: 1, want: true},
+		{name: "target", col: 3, row: 1, want: true},
+		{name: "box", col: 5, row: 1},
+		{name: "brick", col: 0, row: 0},
+		{name: "outside", col: 9, row: 9},
+		{name: "after a move", col: 4, row: 1, moves: []Course{Right}},
+		{name: "forward level", col: 2, row: 1, forward: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := LoadLevel(strings.NewReader("#######\n#@ $ .#\n#######"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.forward {
+				l = l.Reverse()
+			}
+			for _, c := range tt.moves {
+				l.Move(c)
+			}
+			if got := l.PlacePlayer(tt.col, tt.row); got != tt.want {
+				t.Errorf("PlacePlayer(%d, %d) = %v, want %v", tt.col, tt.row, got, tt.want)
+			}
+			if col, row := l.PlayerPosition(); tt.want && (col != tt.col || row != tt.row) {
+				t.Errorf("PlayerPosition() = %d, %d, want %d, %d", col, row, tt.col, tt.row)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package gokoban
+
+type Transformation int
+
+const (
+	Identity Transformation = iota
+	Rotate90
+	Rotate180
+	Rotate270
+	MirrorHorizontal
+	MirrorVertical
+	MirrorDiagonal
+	MirrorAntiDiagonal
+)
+
+var Transformations = []Transformation{
+	Identity,
+	Rotate90,
+	Rotate180,
+	Rotate270,
+	MirrorHorizontal,
+	MirrorVertical,
+	MirrorDiagonal,
+	MirrorAntiDiagonal,
+}
+
+func (t Transformation) String() string {
+	switch t {
+	case Identity:
+		return "identity"
+	case Rotate90:
+		return "rotate 90"
+	case Rotate180:
+		return "rotate 180"
+	case Rotate270:
+		return "rotate 270"
+	case MirrorHorizontal:
+		return "mirror horizontally"
+	case MirrorVertical:
+		return "mirror vertically"
+	case MirrorDiagonal:
+		return "mirror diagonally"
+	default:
+		return "mirror anti-diagonally"
+	}
+}
+
+// Inverse returns the transformation that undoes t.
+func (t Transformation) Inverse() Transformation {
+	switch t {
+	case Rotate90:
+		return Rotate270
+	case Rotate270:
+		return Rotate90
+	default:
+		return t
+	}
+}
+
+// swapsAxes reports whether t turns a width x height board into a
+// height x width one.
+func (t Transformation) swapsAxes() bool {
+	return t == Rotate90 || t == Rotate270 || t == MirrorDiagonal || t == MirrorAntiDiagonal
+}
+
+// point maps col, row of a width x height board. Rotations are clockwise,
+// mirroring horizontally swaps left and right.
+func (t Transformation) point(col, row, width, height int) (int, int) {
+	switch t {
+	case Rotate90:
+		return height - 1 - row, col
+	case Rotate180:
+		return width - 1 - col, height - 1 - row
+	case Rotate270:
+		return row, width - 1 - col
+	case MirrorHorizontal:
+		return width - 1 - col, row
+	case MirrorVertical:
+		return col, height - 1 - row
+	case MirrorDiagonal:
+		return row, col
+	case MirrorAntiDiagonal:
+		return height - 1 - row, width - 1 - col
+	default:
+		return col, row
+	}
+}
+
+// Course maps a course the same way the board is mapped.
+func (t Transformation) Course(c Course) Course {
+	dc, dr := getRelativeMovement(c)
+	c0, r0 := t.point(1, 1, 3, 3)
+	c1, r1 := t.point(1+dc, 1+dr, 3, 3)
+	for _, course := range []Course{Up, Right, Down, Left} {
+		if rc, rr := getRelativeMovement(course); rc == c1-c0 && rr == r1-r0 {
+			return course
+		}
+	}
+	return c
+}
+
+// Transform returns a transformed copy of the level including its moves
+// made so far and its solution.
+func (l *Level) Transform(t Transformation) *Level {
+	width, height := l.width, l.height
+	if t.swapsAxes() {
+		width, height = height, width
+	}
+
+	transformed := &Level{
+		width:          width,
+		height:         height,
+		kinds:          make([]fieldKind, len(l.kinds)),
+		curr:           make([]fieldKind, len(l.curr)),
+		Title:          l.Title,
+		Author:         l.Author,
+		Comment:        l.Comment,
+		Solution:       make([]Course, len(l.Solution)),
+		reverse:        l.reverse,
+		personalMoves:  l.personalMoves,
+		personalPushes: l.personalPushes,
+	}
+	for pos, k := range l.kinds {
+		c, r := t.point(pos%l.width, pos/l.width, l.width, l.height)
+		transformed.kinds[transformed.pos(c, r)] = k
+	}
+	sc, sr := t.point(l.start%l.width, l.start/l.width, l.width, l.height)
+	transformed.start = transformed.pos(sc, sr)
+	transformed.Reset()
+	transformed.dead = transformed.deadSquares()
+
+	for i, c := range l.Solution {
+		transformed.Solution[i] = t.Course(c)
+	}
+	transformed.solutionPushes = l.solutionPushes
+
+	transformed.copyHistory(l, t.Course)
+	for _, m := range l.moves {
+		transformed.replay(&move{
+			course:   t.Course(m.course),
+			movedBox: m.movedBox,
+		})
+	}
+
+	return transformed
+}
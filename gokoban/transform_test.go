@@ -0,0 +1,65 @@
+package gokoban
+
+import (
+	"testing"
+)
+
+func TestTransformInverse(t *testing.T) {
+	l := play(t, "#######\n#@ $ .#\n#   # #\n#######", "d")
+	solution, err := ParseLURD("rRR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetSolution(solution)
+
+	for _, tr := range Transformations {
+		t.Run(tr.String(), func(t *testing.T) {
+			transformed := l.Transform(tr)
+			if v := transformed.Verify(); !v.Valid {
+				t.Errorf("transformed solution fails at step %d: %v", v.Step, v.Err)
+			}
+			if transformed.MoveCount() != 1 || transformed.NodeCount() != l.NodeCount() {
+				t.Errorf("transformed level has %d moves and %d nodes, want 1 and %d", transformed.MoveCount(), transformed.NodeCount(), l.NodeCount())
+			}
+
+			back := transformed.Transform(tr.Inverse())
+			if got, want := back.String(), l.String(); got != want {
+				t.Errorf("Transform(%v).Transform(%v) =\n%v\nwant\n%v", tr, tr.Inverse(), got, want)
+			}
+			if got, want := back.FormatLURD(back.Solution), "rRR"; got != want {
+				t.Errorf("solution after Inverse = %q, want %q", got, want)
+			}
+			if got, want := back.Moves(), "d"; got != want {
+				t.Errorf("moves after Inverse = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTransformationCourse(t *testing.T) {
+	tests := []struct {
+		tr   Transformation
+		in   Course
+		want Course
+	}{
+		{Identity, Up, Up},
+		{Rotate90, Up, Right},
+		{Rotate90, Right, Down},
+		{Rotate180, Left, Right},
+		{Rotate270, Up, Left},
+		{MirrorHorizontal, Left, Right},
+		{MirrorHorizontal, Up, Up},
+		{MirrorVertical, Up, Down},
+		{MirrorVertical, Right, Right},
+		{MirrorDiagonal, Right, Down},
+		{MirrorAntiDiagonal, Right, Up},
+	}
+	for _, tt := range tests {
+		if got := tt.tr.Course(tt.in); got != tt.want {
+			t.Errorf("%v.Course(%v) = %v, want %v", tt.tr, tt.in, got, tt.want)
+		}
+		if got := tt.tr.Inverse().Course(tt.tr.Course(tt.in)); got != tt.in {
+			t.Errorf("%v.Course(%v.Course(%v)) = %v, want %v", tt.tr.Inverse(), tt.tr, tt.in, got, tt.in)
+		}
+	}
+}
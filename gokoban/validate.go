@@ -0,0 +1,57 @@
+package gokoban
+
+// Validate checks the level as loaded and lists every problem found. The
+// area the player can walk to, ignoring boxes, must be enclosed by bricks
+// and contain every box and target.
+func (l *Level) Validate() Problems {
+	var problems Problems
+
+	boxCnt := 0
+	targetCnt := 0
+	playerFound := false
+	for pos, k := range l.kinds {
+		switch k {
+		case box:
+			boxCnt++
+		case target:
+			targetCnt++
+		case player, playerOnTarget:
+			if playerFound {
+				problems = append(problems, newLevelError(ErrMultiplePlayers, pos%l.width, pos/l.width))
+			}
+			playerFound = true
+		}
+		if k == playerOnTarget {
+			targetCnt++
+		}
+	}
+
+	if !playerFound {
+		problems = append(problems, newLevelError(ErrNoPlayer, -1, -1))
+	}
+	if boxCnt == 0 {
+		problems = append(problems, newLevelError(ErrNoBoxes, -1, -1))
+	}
+	if boxCnt != targetCnt {
+		problems = append(problems, newLevelError(ErrBoxTargetMismatch, -1, -1))
+	}
+	if !playerFound {
+		return problems
+	}
+
+	inside := l.inside()
+	for pos, reachable := range inside {
+		c, r := pos%l.width, pos/l.width
+		k := l.kinds[pos]
+		switch {
+		case reachable && (c == 0 || r == 0 || c == l.width-1 || r == l.height-1):
+			problems = append(problems, newLevelError(ErrOpenBorder, c, r))
+		case !reachable && (k == box || k == boxOnTarget):
+			problems = append(problems, newLevelError(ErrUnreachableBox, c, r))
+		case !reachable && k.isTarget():
+			problems = append(problems, newLevelError(ErrUnreachableTarget, c, r))
+		}
+	}
+
+	return problems
+}
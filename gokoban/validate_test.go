@@ -0,0 +1,69 @@
+package gokoban
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     error
+		col, row int
+	}{
+		{name: "no player", input: "#####\n# $.#\n#####", want: ErrNoPlayer, col: -1, row: -1},
+		{name: "multiple players", input: "#####\n#@$.#\n#@  #\n#####", want: ErrMultiplePlayers, col: 1, row: 2},
+		{name: "no boxes", input: "####\n#@ #\n####", want: ErrNoBoxes, col: -1, row: -1},
+		{name: "box target mismatch", input: "#####\n#@$ #\n#####", want: ErrBoxTargetMismatch, col: -1, row: -1},
+		{name: "open border", input: "#####\n#@$. \n#####", want: ErrOpenBorder, col: 4, row: 1},
+		{name: "unreachable box", input: "########\n#@$..#$#\n########", want: ErrUnreachableBox, col: 6, row: 1},
+		{name: "unreachable target", input: "#########\n#@$$.#.##\n#########", want: ErrUnreachableTarget, col: 6, row: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadLevel(strings.NewReader(tt.input))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("LoadLevel() error = %v, want %v", err, tt.want)
+			}
+			var problems Problems
+			if !errors.As(err, &problems) {
+				t.Fatalf("LoadLevel() error = %T, want Problems", err)
+			}
+			for _, p := range problems {
+				if p.Err != tt.want {
+					continue
+				}
+				if p.Col != tt.col || p.Row != tt.row {
+					t.Errorf("%v at %d, %d, want %d, %d", p.Err, p.Col, p.Row, tt.col, tt.row)
+				}
+				return
+			}
+			t.Errorf("problems %v do not contain %v", problems, tt.want)
+		})
+	}
+}
+
+func TestProblems(t *testing.T) {
+	_, err := LoadLevel(strings.NewReader("#####\n#@@ #\n#####"))
+	for _, want := range []error{ErrMultiplePlayers, ErrNoBoxes} {
+		if !errors.Is(err, want) {
+			t.Errorf("errors.Is(%v, %v) = false, want true", err, want)
+		}
+	}
+	if errors.Is(err, ErrOpenBorder) {
+		t.Errorf("errors.Is(%v, %v) = true, want false", err, ErrOpenBorder)
+	}
+
+	var le *LevelError
+	if !errors.As(err, &le) {
+		t.Fatalf("errors.As(%v, *LevelError) = false, want true", err)
+	}
+	if le.Err != ErrMultiplePlayers || le.Col != 2 || le.Row != 1 {
+		t.Errorf("first problem = %v at %d, %d, want %v at 2, 1", le.Err, le.Col, le.Row, ErrMultiplePlayers)
+	}
+	if got, want := err.Error(), "level has more than one player at row 2, column 3; level has no boxes"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package gokoban
+
+import (
+	"errors"
+)
+
+var (
+	ErrNoSolution   = errors.New("level has no solution")
+	ErrIllegalMove  = errors.New("illegal move")
+	ErrNotCompleted = errors.New("solution does not complete the level")
+)
+
+type Verification struct {
+	Valid bool
+	// Step is the zero-based index of the first step that goes wrong, or
+	// the solution length if all steps are legal but the level is not
+	// completed. It is -1 for valid solutions.
+	Step   int
+	Err    error
+	Moves  int
+	Pushes int
+}
+
+func (l *Level) Verify() *Verification {
+	return l.VerifySolution(l.Solution)
+}
+
+// VerifySolution replays solution from the initial position without
+// touching the current position.
+func (l *Level) VerifySolution(solution []Course) *Verification {
+	v := &Verification{
+		Step: -1,
+	}
+	if len(solution) == 0 {
+		v.Step = 0
+		v.Err = ErrNoSolution
+		return v
+	}
+
+	l.simulate(func() {
+		for i, c := range solution {
+			if !l.CanMove(c) {
+				v.Step = i
+				v.Err = ErrIllegalMove
+				break
+			}
+			l.Move(c)
+		}
+		v.Moves = l.MoveCount()
+		v.Pushes = l.PushCount()
+		if v.Err == nil && !l.Completed() {
+			v.Step = len(solution)
+			v.Err = ErrNotCompleted
+		}
+	})
+
+	v.Valid = v.Err == nil
+	return v
+}
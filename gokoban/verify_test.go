@@ -0,0 +1,45 @@
+package gokoban
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifySolution(t *testing.T) {
+	l, err := LoadLevel(strings.NewReader("######\n#@ $.#\n######"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		solution string
+		err      error
+		step     int
+		moves    int
+		pushes   int
+	}{
+		{name: "valid", solution: "rR", err: nil, step: -1, moves: 2, pushes: 1},
+		{name: "no solution", solution: "", err: ErrNoSolution, step: 0},
+		{name: "illegal first move", solution: "lrR", err: ErrIllegalMove, step: 0},
+		{name: "illegal push", solution: "rRR", err: ErrIllegalMove, step: 2},
+		{name: "not completed", solution: "rl", err: ErrNotCompleted, step: 2, moves: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			solution, err := ParseLURD(tt.solution)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := l.VerifySolution(solution)
+			if v.Err != tt.err || v.Step != tt.step || v.Valid != (tt.err == nil) {
+				t.Errorf("VerifySolution() = %v at step %d, want %v at step %d", v.Err, v.Step, tt.err, tt.step)
+			}
+			if tt.moves > 0 && (v.Moves != tt.moves || v.Pushes != tt.pushes) {
+				t.Errorf("VerifySolution() = %d moves, %d pushes, want %d, %d", v.Moves, v.Pushes, tt.moves, tt.pushes)
+			}
+		})
+	}
+	if l.MoveCount() != 0 {
+		t.Errorf("VerifySolution() left %d moves, want 0", l.MoveCount())
+	}
+}
@@ -1,7 +1,14 @@
 package main
 
-import "github.com/x-cellent/gokoban/console"
+import (
+	"github.com/x-cellent/gokoban/cli"
+	"github.com/x-cellent/gokoban/console"
+	"os"
+)
 
 func main() {
+	if cli.Run(os.Args[1:]) {
+		return
+	}
 	console.Run()
 }
@@ -0,0 +1,153 @@
+package profile
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/x-cellent/gokoban/gokoban"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Solution struct {
+	Moves  int    `json:"moves"`
+	Pushes int    `json:"pushes"`
+	LURD   string `json:"lurd"`
+}
+
+// Record holds the achievements of the player on one level. BestMoves and
+// BestPushes are nil until the level is solved.
+type Record struct {
+	BestMoves   *Solution `json:"bestMoves,omitempty"`
+	BestPushes  *Solution `json:"bestPushes,omitempty"`
+	FirstSolved time.Time `json:"firstSolved"`
+	LastSolved  time.Time `json:"lastSolved"`
+	Attempts    int       `json:"attempts"`
+}
+
+func (r *Record) Solved() bool {
+	return r.BestMoves != nil
+}
+
+// Profile stores one record file per level in a directory. Levels are
+// identified by their initial position, so records follow a level into
+// other collections.
+type Profile struct {
+	dir string
+}
+
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gokoban", "profile"), nil
+}
+
+func Open(dir string) (*Profile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Profile{dir: dir}, nil
+}
+
+// Key identifies a level by its bricks, targets, boxes and player as
+// loaded.
+func Key(l *gokoban.Level) string {
+	var sb strings.Builder
+	pc, pr := l.InitialPlayerPosition()
+	_, _ = fmt.Fprintf(&sb, "%dx%d@%d,%d\n", l.Width(), l.Height(), pc, pr)
+	for r := 0; r < l.Height(); r++ {
+		for c := 0; c < l.Width(); c++ {
+			switch {
+			case l.IsBrick(c, r):
+				sb.WriteString(gokoban.BrickSymbol)
+			case l.IsInitialBox(c, r) && l.IsTarget(c, r):
+				sb.WriteString(gokoban.BoxOnTargetSymbol)
+			case l.IsInitialBox(c, r):
+				sb.WriteString(gokoban.BoxSymbol)
+			case l.IsTarget(c, r):
+				sb.WriteString(gokoban.TargetSymbol)
+			default:
+				sb.WriteString(gokoban.FreeSymbol)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sum := sha1.Sum([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (p *Profile) path(l *gokoban.Level) string {
+	return filepath.Join(p.dir, Key(l)+".json")
+}
+
+// Record returns the record of l, which is empty if l was never played.
+func (p *Profile) Record(l *gokoban.Level) (*Record, error) {
+	r := &Record{}
+	bb, err := ioutil.ReadFile(p.path(l))
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bb, r); err != nil {
+		return nil, fmt.Errorf("record %q is not valid: %w", p.path(l), err)
+	}
+	return r, nil
+}
+
+func (p *Profile) save(l *gokoban.Level, r *Record) error {
+	bb, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p.path(l), bb, 0644)
+}
+
+func (p *Profile) AddAttempt(l *gokoban.Level) (*Record, error) {
+	r, err := p.Record(l)
+	if err != nil {
+		return nil, err
+	}
+	r.Attempts++
+	return r, p.save(l, r)
+}
+
+// AddSolution records the moves made on the completed level l. The best
+// solutions are only replaced by better ones, improved reports whether any
+// of them was.
+func (p *Profile) AddSolution(l *gokoban.Level, solved time.Time) (r *Record, improved bool, err error) {
+	if !l.Completed() {
+		return nil, false, fmt.Errorf("level is not completed")
+	}
+	r, err = p.Record(l)
+	if err != nil {
+		return nil, false, err
+	}
+
+	s := &Solution{
+		Moves:  l.MoveCount(),
+		Pushes: l.PushCount(),
+		LURD:   l.Moves(),
+	}
+	if b := r.BestMoves; b == nil || s.Moves < b.Moves || (s.Moves == b.Moves && s.Pushes < b.Pushes) {
+		r.BestMoves = s
+		improved = true
+	}
+	if b := r.BestPushes; b == nil || s.Pushes < b.Pushes || (s.Pushes == b.Pushes && s.Moves < b.Moves) {
+		r.BestPushes = s
+		improved = true
+	}
+	if r.FirstSolved.IsZero() {
+		r.FirstSolved = solved
+	}
+	r.LastSolved = solved
+
+	return r, improved, p.save(l, r)
+}
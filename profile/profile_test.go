@@ -0,0 +1,134 @@
+package profile
+
+import (
+	"github.com/x-cellent/gokoban/gokoban"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const room = "######\n#@   #\n# $  #\n#   .#\n######"
+
+func TestAddSolution(t *testing.T) {
+	p, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		moves        string
+		wantImproved bool
+		wantMoves    string
+		wantPushes   string
+	}{
+		{name: "first", moves: "rlrDldRR", wantImproved: true, wantMoves: "rlrDldRR", wantPushes: "rlrDldRR"},
+		{name: "fewer moves", moves: "rDldRR", wantImproved: true, wantMoves: "rDldRR", wantPushes: "rDldRR"},
+		{name: "same", moves: "rDldRR", wantImproved: false, wantMoves: "rDldRR", wantPushes: "rDldRR"},
+		{name: "more moves", moves: "rrllrDldRR", wantImproved: false, wantMoves: "rDldRR", wantPushes: "rDldRR"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := play(t, tt.moves)
+			r, improved, err := p.AddSolution(l, start.Add(time.Duration(i)*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if improved != tt.wantImproved {
+				t.Errorf("AddSolution() improved = %v, want %v", improved, tt.wantImproved)
+			}
+			if r.BestMoves.LURD != tt.wantMoves || r.BestPushes.LURD != tt.wantPushes {
+				t.Errorf("best = %q, %q, want %q, %q", r.BestMoves.LURD, r.BestPushes.LURD, tt.wantMoves, tt.wantPushes)
+			}
+			if !r.FirstSolved.Equal(start) || !r.LastSolved.Equal(start.Add(time.Duration(i)*time.Hour)) {
+				t.Errorf("solved %v first, %v last", r.FirstSolved, r.LastSolved)
+			}
+		})
+	}
+
+	if _, _, err := p.AddSolution(play(t, "rD"), start); err == nil {
+		t.Error("AddSolution() of an incomplete level error = nil, want an error")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	dir := t.TempDir()
+	p, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := play(t, "")
+
+	r, err := p.Record(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Solved() || r.Attempts != 0 {
+		t.Errorf("Record() of a new level = %+v, want an empty record", r)
+	}
+
+	for i := 1; i <= 2; i++ {
+		if r, err = p.AddAttempt(play(t, "rr")); err != nil {
+			t.Fatal(err)
+		}
+		if r.Attempts != i {
+			t.Errorf("AddAttempt() = %d attempts, want %d", r.Attempts, i)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, Key(l)+".json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Record(l); err == nil {
+		t.Error("Record() of a broken file error = nil, want an error")
+	}
+}
+
+func TestKey(t *testing.T) {
+	l := play(t, "")
+	tests := []struct {
+		name  string
+		other *gokoban.Level
+		same  bool
+	}{
+		{name: "moves made", other: play(t, "rDl"), same: true},
+		{name: "transformed", other: l.Transform(gokoban.MirrorHorizontal), same: false},
+		{name: "reversed", other: l.Reverse(), same: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Key(tt.other) == Key(l); got != tt.same {
+				t.Errorf("Key() equal = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestOpenCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	if _, err := Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		t.Errorf("Open() did not create %s: %v", dir, err)
+	}
+}
+
+func play(t *testing.T, moves string) *gokoban.Level {
+	t.Helper()
+	l, err := gokoban.LoadLevel(strings.NewReader(room))
+	if err != nil {
+		t.Fatal(err)
+	}
+	courses, err := gokoban.ParseLURD(moves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range courses {
+		l.Move(c)
+	}
+	return l
+}
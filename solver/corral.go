@@ -0,0 +1,169 @@
+package solver
+
+import (
+	"github.com/x-cellent/gokoban/gokoban"
+)
+
+// corralLimit is the maximum number of positions searched to prove that a
+// corral is a deadlock.
+const corralLimit = 500
+
+// A corral is an area the player cannot reach because boxes fence it in.
+// If every push of the fencing boxes leads into the corral and the player
+// can reach all of them (a PI-corral), those pushes have to come first in
+// some solution, so all other pushes can be skipped. A corral whose boxes
+// cannot be solved even with all other boxes gone is a deadlock.
+type corral struct {
+	id     int
+	cells  []int
+	fence  []int
+	pushes []push
+	pi     bool
+	done   bool
+}
+
+type push struct {
+	box    int
+	course gokoban.Course
+}
+
+// corralPushes returns the pushes the search can be restricted to because
+// of corrals: none at all for a deadlock, or those of the PI-corral with
+// the fewest pushes. ok is false if the corrals don't restrict anything.
+func (b *board) corralPushes(player int, dist []int, boxes []int) (pushes []push, ok bool) {
+	area := make([]int, len(b.walls))
+	var best *corral
+	id := 0
+	for p := range b.walls {
+		if b.walls[p] || dist[p] >= 0 || area[p] != 0 || hasBox(boxes, p) {
+			continue
+		}
+		id++
+		c := b.corral(dist, boxes, area, p, id)
+		if c.done {
+			continue
+		}
+		if b.corralDeadlocked(player, c) {
+			return nil, true
+		}
+		if c.pi && (best == nil || len(c.pushes) < len(best.pushes)) {
+			best = c
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	return best.pushes, true
+}
+
+// corral fills the area around p with id. Corrals that share boxes with
+// other corrals are never PI-corrals, their boxes might be pushed from the
+// other side.
+func (b *board) corral(dist []int, boxes []int, area []int, p, id int) *corral {
+	c := &corral{id: id, pi: true, done: true}
+	area[p] = id
+	queue := []int{p}
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		c.cells = append(c.cells, q)
+		if b.targets[q] {
+			c.done = false
+		}
+		for _, course := range courses {
+			to := q + b.delta(course)
+			switch {
+			case b.walls[to] || area[to] == id:
+			case hasBox(boxes, to):
+				if !hasBox(c.fence, to) {
+					c.fence = moveBox(c.fence, -1, to)
+				}
+			default:
+				area[to] = id
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	for _, box := range c.fence {
+		if !b.targets[box] {
+			c.done = false
+		}
+		for _, course := range courses {
+			d := b.delta(course)
+			stand, behind := box-d, box+d
+			free := !b.walls[behind] && !hasBox(boxes, behind)
+			if free && dist[behind] < 0 && area[behind] != id {
+				c.pi = false
+			}
+			if b.walls[stand] || area[stand] == id || !free || b.dead[behind] {
+				continue
+			}
+			switch {
+			case area[behind] != id:
+				// not I: the box might be pushed elsewhere
+				c.pi = false
+			case dist[stand] < 0:
+				// not P: the player cannot get behind the box yet
+				c.pi = false
+			default:
+				c.pushes = append(c.pushes, push{box: box, course: course})
+			}
+		}
+	}
+	return c
+}
+
+// corralDeadlocked searches whether the fence of c can be pushed so that
+// the player gets into the corral or all of its boxes are on targets, with
+// all other boxes removed. Fewer boxes never make a level harder, so if
+// that fails, the position is a deadlock. Searches that exceed corralLimit
+// count as solvable.
+func (b *board) corralDeadlocked(player int, c *corral) bool {
+	start := state{player: player, boxes: c.fence}
+	key := b.positionKey(start)
+	if deadlock, ok := b.deadlocks[key]; ok {
+		return deadlock
+	}
+
+	seen := map[string]bool{key: true}
+	queue := []state{start}
+	deadlock := true
+	for len(queue) > 0 && deadlock {
+		s := queue[0]
+		queue = queue[1:]
+		if len(seen) > corralLimit || b.solved(s.boxes) {
+			deadlock = false
+			break
+		}
+
+		dist := b.distances(s.player, s.boxes)
+		for _, q := range c.cells {
+			if dist[q] >= 0 {
+				deadlock = false
+				break
+			}
+		}
+		for _, p := range s.boxes {
+			for _, course := range courses {
+				d := b.delta(course)
+				stand, behind := p-d, p+d
+				if dist[stand] < 0 || b.walls[behind] || b.dead[behind] || hasBox(s.boxes, behind) {
+					continue
+				}
+				boxes := moveBox(s.boxes, p, behind)
+				if b.blocked(boxes, behind) || (!b.targets[behind] && b.frozen(boxes, behind)) {
+					continue
+				}
+				next := state{player: p, boxes: boxes}
+				if k := b.positionKey(next); !seen[k] {
+					seen[k] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	b.deadlocks[key] = deadlock
+	return deadlock
+}
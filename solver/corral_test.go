@@ -0,0 +1,43 @@
+package solver
+
+import (
+	"github.com/x-cellent/gokoban/gokoban"
+	"reflect"
+	"testing"
+)
+
+func TestCorralPushes(t *testing.T) {
+	type cell struct {
+		col, row int
+		course   gokoban.Course
+	}
+	tests := []struct {
+		name   string
+		input  string
+		want   []cell
+		wantOK bool
+	}{
+		{name: "no corral", input: turnLevel},
+		{name: "PI-corral", input: "#######\n#@ $ .#\n#######", want: []cell{{3, 1, gokoban.Right}}, wantOK: true},
+		{name: "corral deadlock", input: "#########\n#@ $ $..#\n#########", wantOK: true},
+		{name: "box fencing two corrals", input: "#######\n## ####\n#@$  .#\n#  #  #\n#######"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := loadLevel(t, tt.input)
+			b := newBoard(l)
+			pc, pr := l.PlayerPosition()
+			player := b.pos(pc, pr)
+			boxes := b.boxes(l.IsBox)
+
+			var want []push
+			for _, c := range tt.want {
+				want = append(want, push{box: b.pos(c.col, c.row), course: c.course})
+			}
+			pushes, ok := b.corralPushes(player, b.distances(player, boxes), boxes)
+			if ok != tt.wantOK || !reflect.DeepEqual(pushes, want) {
+				t.Errorf("corralPushes() = %v, %v, want %v, %v", pushes, ok, want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package solver
+
+// unreachable is the cost of a box-target pair without any push path.
+const unreachable = 1 << 20
+
+// minMatching solves the assignment problem for a square cost matrix with
+// the Hungarian algorithm and returns the minimal total cost.
+func minMatching(cost [][]int) int {
+	n := len(cost)
+	if n == 0 {
+		return 0
+	}
+	m := len(cost[0])
+
+	u := make([]int, n+1)
+	v := make([]int, m+1)
+	match := make([]int, m+1)
+	way := make([]int, m+1)
+	minv := make([]int, m+1)
+	used := make([]bool, m+1)
+
+	for i := 1; i <= n; i++ {
+		match[0] = i
+		j0 := 0
+		for j := range minv {
+			minv[j] = 1 << 30
+			used[j] = false
+		}
+		for {
+			used[j0] = true
+			i0 := match[j0]
+			delta := 1 << 30
+			j1 := 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				cur := cost[i0-1][j-1] - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if match[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			match[j0] = match[j1]
+			j0 = j1
+		}
+	}
+
+	total := 0
+	for j := 1; j <= m; j++ {
+		if match[j] > 0 {
+			total += cost[match[j]-1][j-1]
+		}
+	}
+	return total
+}
@@ -0,0 +1,238 @@
+package solver
+
+import (
+	"container/heap"
+	"context"
+	"github.com/x-cellent/gokoban/gokoban"
+)
+
+const (
+	// maxWindow is the largest number of consecutive pushes that is searched
+	// for a better replacement.
+	maxWindow = 6
+	// windowLimit is the maximum number of nodes expanded per window.
+	windowLimit = 20000
+)
+
+// Optimize shortens a valid solution of l. The result never has more moves
+// or pushes than solution. Walks between pushes become shortest walks,
+// pushes that lead back to an earlier position are dropped unless that
+// makes the walks longer and windows of a few pushes are replaced by
+// cheaper ones. Once ctx is done the best
+// solution found so far is returned.
+func Optimize(ctx context.Context, l *gokoban.Level, solution []gokoban.Course) ([]gokoban.Course, error) {
+	return OptimizeBudget(ctx, l, solution, 0)
+}
+
+// OptimizeBudget is Optimize with a budget of expanded positions on top of
+// ctx, see SolveBudget. Once it is spent the best solution found so far is
+// returned.
+func OptimizeBudget(ctx context.Context, l *gokoban.Level, solution []gokoban.Course, budget int) ([]gokoban.Course, error) {
+	if v := l.VerifySolution(solution); !v.Valid {
+		return nil, v.Err
+	}
+
+	b := newBoard(l)
+	b.budget = budget
+	pc, pr := l.InitialPlayerPosition()
+	start := state{
+		player: b.pos(pc, pr),
+		boxes:  b.boxes(l.IsInitialBox),
+	}
+	steps := b.split(start, solution)
+	best, pushes := append([]gokoban.Course(nil), solution...), len(steps)-1
+	keep := func(steps []step) {
+		if path := b.expand(steps); better(len(path), len(steps)-1, len(best), pushes) {
+			best, pushes = path, len(steps)-1
+		}
+	}
+	steps = b.removeLoops(steps)
+	keep(steps)
+
+	for improved := true; improved; {
+		improved = false
+		for w := 2; w <= maxWindow; w++ {
+			for i := 0; i+w < len(steps); i++ {
+				if ctx.Err() != nil || (b.budget > 0 && b.spent >= b.budget) {
+					return best, nil
+				}
+				if cheaper, ok := b.improveWindow(steps, i, w, len(best)); ok {
+					steps = b.removeLoops(cheaper)
+					keep(steps)
+					improved = true
+				}
+			}
+		}
+	}
+
+	return best, nil
+}
+
+// step is the position right after a push together with the course of
+// that push. The first step of a solution is the start position.
+type step struct {
+	state
+	course gokoban.Course
+}
+
+// split breaks a solution down into its pushes.
+func (b *board) split(start state, solution []gokoban.Course) []step {
+	steps := []step{{state: start}}
+	s := start
+	for _, c := range solution {
+		to := s.player + b.delta(c)
+		if hasBox(s.boxes, to) {
+			s = state{player: to, boxes: moveBox(s.boxes, to, to+b.delta(c))}
+			steps = append(steps, step{state: s, course: c})
+			continue
+		}
+		s.player = to
+	}
+	return steps
+}
+
+// expand builds the moves of steps with shortest walks between pushes.
+func (b *board) expand(steps []step) []gokoban.Course {
+	path := []gokoban.Course{}
+	for i := 1; i < len(steps); i++ {
+		from, to := steps[i-1], steps[i]
+		path = append(path, b.walk(from.player, to.player-b.delta(to.course), from.boxes)...)
+		path = append(path, to.course)
+	}
+	return path
+}
+
+// better reports whether a solution with the given moves and pushes beats
+// the best one so far: fewer moves, or as many moves and fewer pushes.
+func better(moves, pushes, bestMoves, bestPushes int) bool {
+	return moves < bestMoves || (moves == bestMoves && pushes < bestPushes)
+}
+
+// removeLoops drops the pushes between two steps with equal boxes and
+// player region unless the walks around them get longer than the pushes
+// they save.
+func (b *board) removeLoops(steps []step) []step {
+	seen := make(map[string]int)
+	var kept []step
+	for j, s := range steps {
+		k := b.key(&node{state: s.state}, PushOptimal)
+		if i, ok := seen[k]; ok && b.shorterWithout(kept, i, steps[j:]) {
+			for _, dropped := range kept[i+1:] {
+				delete(seen, b.key(&node{state: dropped.state}, PushOptimal))
+			}
+			kept = kept[:i+1]
+			continue
+		}
+		seen[k] = len(kept)
+		kept = append(kept, s)
+	}
+	return kept
+}
+
+// shorterWithout reports whether dropping kept[i+1:] and rest[0], which
+// ends at the same position as kept[i], does not add moves.
+func (b *board) shorterWithout(kept []step, i int, rest []step) bool {
+	with := append(append([]step(nil), kept...), rest...)
+	without := append(append([]step(nil), kept[:i+1]...), rest[1:]...)
+	return len(b.expand(without)) <= len(b.expand(with))
+}
+
+// improveWindow searches a cheaper way from steps[i] to steps[i+w] with at
+// most w pushes. The result is only accepted if the whole solution gets
+// fewer moves or pushes without getting worse in the other.
+func (b *board) improveWindow(steps []step, i, w, moves int) ([]step, bool) {
+	from, to := steps[i], steps[i+w]
+	bound := len(b.expand(steps[i : i+w+1]))
+
+	root := &node{state: from.state}
+	best := map[string]int{b.key(root, MoveOptimal): 0}
+	open := &queue{root}
+	for expanded := 0; open.Len() > 0 && expanded < windowLimit && b.spend(); expanded++ {
+		n := heap.Pop(open).(*node)
+		if g, ok := best[b.key(n, MoveOptimal)]; ok && g < n.g {
+			continue
+		}
+
+		pushes := depth(n)
+		if n != root && equalBoxes(n.boxes, to.boxes) && b.reachable(n.player, n.boxes)[to.player] {
+			if n.g < bound || pushes < w {
+				candidate := append([]step(nil), steps[:i+1]...)
+				candidate = append(candidate, windowSteps(n)...)
+				candidate = append(candidate, steps[i+w+1:]...)
+				m := len(b.expand(candidate))
+				if m <= moves && len(candidate) <= len(steps) && (m < moves || len(candidate) < len(steps)) {
+					return candidate, true
+				}
+			}
+			continue
+		}
+		if pushes == w {
+			continue
+		}
+
+		dist := b.distances(n.player, n.boxes)
+		for _, p := range n.boxes {
+			for _, c := range courses {
+				d := b.delta(c)
+				stand := p - d
+				behind := p + d
+				if dist[stand] < 0 || b.walls[behind] || b.dead[behind] || hasBox(n.boxes, behind) {
+					continue
+				}
+				boxes := moveBox(n.boxes, p, behind)
+				if b.blocked(boxes, behind) || (!b.targets[behind] && b.frozen(boxes, behind)) {
+					continue
+				}
+
+				child := &node{
+					state:  state{player: p, boxes: boxes},
+					parent: n,
+					course: c,
+					g:      n.g + dist[stand] + 1,
+				}
+				if child.g > bound {
+					continue
+				}
+				k := b.key(child, MoveOptimal)
+				if g, ok := best[k]; ok && g <= child.g {
+					continue
+				}
+				best[k] = child.g
+				child.f = child.g
+				heap.Push(open, child)
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func depth(n *node) int {
+	d := 0
+	for ; n.parent != nil; n = n.parent {
+		d++
+	}
+	return d
+}
+
+// windowSteps returns the steps from the window root to n, excluding the
+// root itself.
+func windowSteps(n *node) []step {
+	var steps []step
+	for ; n.parent != nil; n = n.parent {
+		steps = append([]step{{state: n.state, course: n.course}}, steps...)
+	}
+	return steps
+}
+
+func equalBoxes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package solver
+
+import (
+	"context"
+	"github.com/x-cellent/gokoban/gokoban"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		solution string
+		want     string
+	}{
+		{name: "already optimal", input: "#######\n#@ $ .#\n#######", solution: "rRR", want: "rRR"},
+		{name: "detour walk", input: "#######\n#@ $ .#\n#######", solution: "rlrRR", want: "rRR"},
+		{name: "walk around the room", input: turnLevel, solution: "rrrlllrDldRR", want: "rDldRR"},
+		{name: "pushed back and forth", input: turnLevel, solution: "dRurrdLulDldRR", want: "rDldRR"},
+		{name: "push loop saves walking", input: loopLevel, solution: "UrruullDurrdR", want: "UrruRlullD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := loadLevel(t, tt.input)
+			solution, err := gokoban.ParseLURD(tt.solution)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := l.VerifySolution(solution)
+
+			optimized, err := Optimize(context.Background(), l, solution)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after := l.VerifySolution(optimized)
+			if !after.Valid {
+				t.Fatalf("Optimize() = %q fails at step %d: %v", l.FormatLURD(optimized), after.Step, after.Err)
+			}
+			if after.Moves > before.Moves || after.Pushes > before.Pushes {
+				t.Errorf("Optimize() = %d moves, %d pushes, more than %d, %d", after.Moves, after.Pushes, before.Moves, before.Pushes)
+			}
+			if got := l.FormatLURD(optimized); got != tt.want {
+				t.Errorf("Optimize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// loopLevel can be solved with a push up and back down on the way to the
+// other box, which is shorter than the walk around without it.
+const loopLevel = "#######\n#   ###\n# # $.#\n#*  ###\n#@#   #\n# ### #\n# ### #\n# ### #\n#     #\n#######"
+
+func TestOptimizeInvalid(t *testing.T) {
+	l := loadLevel(t, "#######\n#@ $ .#\n#######")
+	tests := []struct {
+		name     string
+		solution []gokoban.Course
+		want     error
+	}{
+		{name: "empty", want: gokoban.ErrNoSolution},
+		{name: "illegal", solution: []gokoban.Course{gokoban.Left}, want: gokoban.ErrIllegalMove},
+		{name: "incomplete", solution: []gokoban.Course{gokoban.Right}, want: gokoban.ErrNotCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Optimize(context.Background(), l, tt.solution); err != tt.want {
+				t.Errorf("Optimize() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptimizeBudget(t *testing.T) {
+	l := loadLevel(t, turnLevel)
+	solution, err := gokoban.ParseLURD("dRurrdLulDldRR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		budget int
+		want   string
+	}{
+		{name: "unlimited", budget: 0, want: "rDldRR"},
+		{name: "loops are removed without searching", budget: 1, want: "rDldRR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optimized, err := OptimizeBudget(context.Background(), l, solution, tt.budget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := l.FormatLURD(optimized); got != tt.want {
+				t.Errorf("OptimizeBudget() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,646 @@
+package solver
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"github.com/x-cellent/gokoban/gokoban"
+	"sort"
+)
+
+type Mode int
+
+// Fast finds any solution, usually much sooner than the optimal modes on
+// larger levels but with more moves and pushes.
+const (
+	MoveOptimal Mode = iota
+	PushOptimal
+	Fast
+)
+
+func (m Mode) String() string {
+	switch m {
+	case MoveOptimal:
+		return "moves"
+	case Fast:
+		return "fast"
+	default:
+		return "pushes"
+	}
+}
+
+var ErrUnsolvable = errors.New("level is unsolvable")
+
+// ErrBudgetExceeded is returned once a search expanded more positions than
+// its budget allows.
+var ErrBudgetExceeded = errors.New("solver budget exceeded")
+
+var courses = []gokoban.Course{gokoban.Up, gokoban.Right, gokoban.Down, gokoban.Left}
+
+// fastWeight weights the lower bound in Fast mode. Nodes close to a
+// solution are expanded first, at the price of longer solutions.
+const fastWeight = 2
+
+// checkInterval is the number of expanded nodes between two context checks.
+const checkInterval = 1024
+
+// Solve searches a solution from the current position of l. In MoveOptimal
+// mode the result has the fewest possible moves, in PushOptimal mode the
+// fewest possible pushes. The level itself is not modified.
+//
+// The search runs until ctx is done, which is its only budget. Levels like
+// the generated ones take push optimal search and Fast mode well under a
+// second, move optimal search takes considerably longer. Levels where ten
+// or more boxes have to enter a goal room in a particular order, like most
+// of the bundled ones from level 3 on, exceed any practical budget.
+func Solve(ctx context.Context, l *gokoban.Level, mode Mode) ([]gokoban.Course, error) {
+	return SolveBudget(ctx, l, mode, 0)
+}
+
+// SolveBudget is Solve with a budget of expanded positions on top of ctx.
+// Unlike a deadline, the budget makes the result independent of the speed
+// of the machine. A budget of 0 or less is unlimited.
+func SolveBudget(ctx context.Context, l *gokoban.Level, mode Mode, budget int) ([]gokoban.Course, error) {
+	b := newBoard(l)
+	b.budget = budget
+	pc, pr := l.PlayerPosition()
+	start := &state{
+		player: b.pos(pc, pr),
+		boxes:  b.boxes(l.IsBox),
+	}
+	return b.solve(ctx, start, mode)
+}
+
+// SolveInitial searches a solution from the initial position of l,
+// regardless of the moves already made.
+func SolveInitial(ctx context.Context, l *gokoban.Level, mode Mode) ([]gokoban.Course, error) {
+	b := newBoard(l)
+	pc, pr := l.InitialPlayerPosition()
+	start := &state{
+		player: b.pos(pc, pr),
+		boxes:  b.boxes(l.IsInitialBox),
+	}
+	return b.solve(ctx, start, mode)
+}
+
+// board is a padded copy of the static part of a level, so that every
+// neighbor of an inner cell is a valid index.
+type board struct {
+	width   int
+	height  int
+	walls   []bool
+	targets []bool
+	dead    []bool
+	pushes  [][]int
+	// deadlocks caches the results of corralDeadlocked.
+	deadlocks map[string]bool
+	// visited and queue are the buffers of region, cells are visited
+	// when they hold the current visit.
+	visited []int
+	queue   []int
+	visit   int
+	// cost is the buffer of lowerBound.
+	cost [][]int
+	// budget is the number of positions searches may expand, if positive,
+	// and spent the number expanded so far.
+	budget int
+	spent  int
+}
+
+func newBoard(l *gokoban.Level) *board {
+	b := &board{
+		width:     l.Width() + 2,
+		height:    l.Height() + 2,
+		deadlocks: map[string]bool{},
+	}
+	n := b.width * b.height
+	b.walls = make([]bool, n)
+	b.targets = make([]bool, n)
+	b.visited = make([]int, n)
+	for r := 0; r < b.height; r++ {
+		for c := 0; c < b.width; c++ {
+			p := r*b.width + c
+			if c == 0 || r == 0 || c == b.width-1 || r == b.height-1 {
+				b.walls[p] = true
+				continue
+			}
+			b.walls[p] = l.IsBrick(c-1, r-1)
+			b.targets[p] = l.IsTarget(c-1, r-1)
+		}
+	}
+	b.pushDistances()
+	return b
+}
+
+func (b *board) pos(col, row int) int {
+	return (row+1)*b.width + col + 1
+}
+
+func (b *board) boxes(isBox func(col, row int) bool) []int {
+	var boxes []int
+	for r := 1; r < b.height-1; r++ {
+		for c := 1; c < b.width-1; c++ {
+			if isBox(c-1, r-1) {
+				boxes = append(boxes, r*b.width+c)
+			}
+		}
+	}
+	return boxes
+}
+
+func (b *board) delta(c gokoban.Course) int {
+	switch c {
+	case gokoban.Up:
+		return -b.width
+	case gokoban.Right:
+		return 1
+	case gokoban.Down:
+		return b.width
+	default:
+		return -1
+	}
+}
+
+// pushDistances computes for every target and cell the fewest pushes a box
+// needs from that cell to the target, by pulling a box backwards from the
+// target. Cells from which no target can be reached are marked dead.
+func (b *board) pushDistances() {
+	b.dead = make([]bool, len(b.walls))
+	for p := range b.dead {
+		b.dead[p] = !b.walls[p]
+	}
+
+	for t, isTarget := range b.targets {
+		if !isTarget {
+			continue
+		}
+		dist := make([]int, len(b.walls))
+		for p := range dist {
+			dist[p] = -1
+		}
+		dist[t] = 0
+		b.dead[t] = false
+		queue := []int{t}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for _, c := range courses {
+				d := b.delta(c)
+				to := p - d
+				if b.walls[to] || b.walls[to-d] || dist[to] >= 0 {
+					continue
+				}
+				dist[to] = dist[p] + 1
+				b.dead[to] = false
+				queue = append(queue, to)
+			}
+		}
+		b.pushes = append(b.pushes, dist)
+	}
+}
+
+func (b *board) solved(boxes []int) bool {
+	for _, p := range boxes {
+		if !b.targets[p] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *board) solve(ctx context.Context, start *state, mode Mode) ([]gokoban.Course, error) {
+	if b.solved(start.boxes) {
+		return []gokoban.Course{}, nil
+	}
+	for _, p := range start.boxes {
+		if b.dead[p] {
+			return nil, ErrUnsolvable
+		}
+	}
+
+	h := b.lowerBound(start.boxes)
+	if h < 0 {
+		return nil, ErrUnsolvable
+	}
+	root := &node{
+		state: *start,
+		f:     h,
+	}
+	best := map[string]int{b.key(root, mode): 0}
+	open := &queue{root}
+
+	for expanded := 0; open.Len() > 0; expanded++ {
+		if expanded%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if !b.spend() {
+			return nil, ErrBudgetExceeded
+		}
+
+		n := heap.Pop(open).(*node)
+		if g, ok := best[b.key(n, mode)]; ok && g < n.g {
+			continue
+		}
+		if b.solved(n.boxes) {
+			return b.path(n), nil
+		}
+
+		dist := b.distances(n.player, n.boxes)
+		pushes, ok := b.tunnelPushes(n, mode)
+		if !ok {
+			pushes, ok = b.corralPushes(n.player, dist, n.boxes)
+			// Reordering pushes keeps their number but not the moves, so
+			// move optimal search only skips corral deadlocks.
+			if ok && len(pushes) > 0 && mode == MoveOptimal {
+				ok = false
+			}
+		}
+		if !ok {
+			pushes = allPushes(n.boxes)
+		}
+		for _, push := range pushes {
+			p, c := push.box, push.course
+			d := b.delta(c)
+			stand := p - d
+			behind := p + d
+			if dist[stand] < 0 || b.walls[behind] || b.dead[behind] || hasBox(n.boxes, behind) {
+				continue
+			}
+			boxes := moveBox(n.boxes, p, behind)
+			if b.blocked(boxes, behind) || (!b.targets[behind] && b.frozen(boxes, behind)) {
+				continue
+			}
+			h := b.lowerBound(boxes)
+			if h < 0 {
+				continue
+			}
+
+			child := &node{
+				state:  state{player: p, boxes: boxes},
+				parent: n,
+				course: c,
+				g:      n.g + 1,
+			}
+			if mode == MoveOptimal {
+				child.g += dist[stand]
+			}
+			k := b.key(child, mode)
+			if g, ok := best[k]; ok && (g <= child.g || mode == Fast) {
+				continue
+			}
+			best[k] = child.g
+			child.f = child.g + h
+			if mode == Fast {
+				child.f = child.g + fastWeight*h
+			}
+			heap.Push(open, child)
+		}
+	}
+
+	return nil, ErrUnsolvable
+}
+
+// spend counts one expanded position and reports whether the budget
+// allowed it.
+func (b *board) spend() bool {
+	if b.budget > 0 && b.spent >= b.budget {
+		return false
+	}
+	b.spent++
+	return true
+}
+
+// tunnelPushes continues the last push of n if it moved a box into a
+// tunnel, a corridor one field wide. The player can't pass the box anyway,
+// so all other pushes might as well be made before it entered the tunnel.
+// This doesn't hold for moves, and boxes may stay on targets.
+func (b *board) tunnelPushes(n *node, mode Mode) ([]push, bool) {
+	if n.parent == nil || mode == MoveOptimal {
+		return nil, false
+	}
+	d := b.delta(n.course)
+	box := n.player + d
+	side := b.width
+	if d == b.width || d == -b.width {
+		side = 1
+	}
+	for _, p := range []int{n.player, box} {
+		if !b.walls[p-side] || !b.walls[p+side] {
+			return nil, false
+		}
+	}
+	if b.targets[box] {
+		return nil, false
+	}
+	return []push{{box: box, course: n.course}}, true
+}
+
+func allPushes(boxes []int) []push {
+	pushes := make([]push, 0, len(courses)*len(boxes))
+	for _, p := range boxes {
+		for _, c := range courses {
+			pushes = append(pushes, push{box: p, course: c})
+		}
+	}
+	return pushes
+}
+
+type state struct {
+	player int
+	boxes  []int
+}
+
+// node is a position right after a push. In MoveOptimal mode g counts
+// moves, otherwise pushes.
+type node struct {
+	state
+	parent *node
+	course gokoban.Course
+	g      int
+	f      int
+}
+
+// key identifies a node. Push optimal search only cares about the region
+// the player can reach, move optimal search needs the exact position.
+func (b *board) key(n *node, mode Mode) string {
+	if mode == MoveOptimal {
+		return stateKey(n.player, n.boxes)
+	}
+	return b.positionKey(n.state)
+}
+
+// positionKey identifies the boxes of s and the region the player can
+// reach.
+func (b *board) positionKey(s state) string {
+	return stateKey(b.region(s.player, s.boxes), s.boxes)
+}
+
+func stateKey(player int, boxes []int) string {
+	bb := make([]byte, 0, 2*(len(boxes)+1))
+	bb = append(bb, byte(player>>8), byte(player))
+	for _, p := range boxes {
+		bb = append(bb, byte(p>>8), byte(p))
+	}
+	return string(bb)
+}
+
+type queue []*node
+
+func (q queue) Len() int {
+	return len(q)
+}
+
+func (q queue) Less(i, j int) bool {
+	if q[i].f == q[j].f {
+		return q[i].g > q[j].g
+	}
+	return q[i].f < q[j].f
+}
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *queue) Push(x interface{}) {
+	*q = append(*q, x.(*node))
+}
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+func hasBox(boxes []int, p int) bool {
+	i := sort.SearchInts(boxes, p)
+	return i < len(boxes) && boxes[i] == p
+}
+
+func moveBox(boxes []int, from, to int) []int {
+	moved := make([]int, 0, len(boxes))
+	for _, p := range boxes {
+		if p != from {
+			moved = append(moved, p)
+		}
+	}
+	i := sort.SearchInts(moved, to)
+	moved = append(moved, 0)
+	copy(moved[i+1:], moved[i:])
+	moved[i] = to
+	return moved
+}
+
+// blocked reports whether the box at p is part of a 2x2 square made only
+// of walls and boxes with at least one box off target.
+func (b *board) blocked(boxes []int, p int) bool {
+	for _, corner := range []int{p, p - 1, p - b.width, p - b.width - 1} {
+		square := []int{corner, corner + 1, corner + b.width, corner + b.width + 1}
+		full := true
+		offTarget := false
+		for _, q := range square {
+			isBox := hasBox(boxes, q)
+			if !isBox && !b.walls[q] {
+				full = false
+				break
+			}
+			if isBox && !b.targets[q] {
+				offTarget = true
+			}
+		}
+		if full && offTarget {
+			return true
+		}
+	}
+	return false
+}
+
+// frozen reports whether the box at p can never be moved again, i.e. it
+// is blocked both horizontally and vertically by walls, dead squares or
+// other frozen boxes.
+func (b *board) frozen(boxes []int, p int) bool {
+	return b.frozenAxis(boxes, p, 1, map[int]bool{}) && b.frozenAxis(boxes, p, b.width, map[int]bool{})
+}
+
+func (b *board) frozenAxis(boxes []int, p, d int, seen map[int]bool) bool {
+	seen[p] = true
+	before, after := p-d, p+d
+	if b.walls[before] || b.walls[after] || seen[before] || seen[after] {
+		return true
+	}
+	if b.dead[before] && b.dead[after] {
+		return true
+	}
+	other := b.width
+	if d != 1 {
+		other = 1
+	}
+	for _, q := range []int{before, after} {
+		if hasBox(boxes, q) && b.frozenAxis(boxes, q, other, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerBound is the cost of a minimum matching between boxes and targets
+// by push distance, or -1 if there is no matching at all.
+func (b *board) lowerBound(boxes []int) int {
+	if len(b.cost) != len(boxes) {
+		b.cost = make([][]int, len(boxes))
+		for i := range b.cost {
+			b.cost[i] = make([]int, len(b.pushes))
+		}
+	}
+	cost := b.cost
+	for i, p := range boxes {
+		for j, dist := range b.pushes {
+			cost[i][j] = dist[p]
+			if cost[i][j] < 0 {
+				cost[i][j] = unreachable
+			}
+		}
+	}
+	h := minMatching(cost)
+	if h >= unreachable {
+		return -1
+	}
+	return h
+}
+
+func (b *board) path(n *node) []gokoban.Course {
+	var chain []*node
+	for ; n != nil; n = n.parent {
+		chain = append(chain, n)
+	}
+
+	var path []gokoban.Course
+	for j := len(chain) - 1; j > 0; j-- {
+		from, to := chain[j], chain[j-1]
+		path = append(path, b.walk(from.player, to.player-b.delta(to.course), from.boxes)...)
+		path = append(path, to.course)
+	}
+	return path
+}
+
+// distances returns the walking distance from player to every cell, or -1
+// for cells that cannot be reached without pushing.
+func (b *board) distances(player int, boxes []int) []int {
+	dist := make([]int, len(b.walls))
+	for p := range dist {
+		dist[p] = -1
+	}
+	dist[player] = 0
+	queue := []int{player}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, c := range courses {
+			to := p + b.delta(c)
+			if dist[to] >= 0 || b.walls[to] || hasBox(boxes, to) {
+				continue
+			}
+			dist[to] = dist[p] + 1
+			queue = append(queue, to)
+		}
+	}
+	return dist
+}
+
+// reachable marks all cells the player can walk to without pushing.
+func (b *board) reachable(player int, boxes []int) []bool {
+	reach := make([]bool, len(b.walls))
+	reach[player] = true
+	queue := []int{player}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, c := range courses {
+			to := p + b.delta(c)
+			if reach[to] || b.walls[to] || hasBox(boxes, to) {
+				continue
+			}
+			reach[to] = true
+			queue = append(queue, to)
+		}
+	}
+	return reach
+}
+
+// region returns the first cell the player can walk to, which identifies
+// the whole region. It runs for every generated node, so it reuses the
+// buffers of b instead of allocating.
+func (b *board) region(player int, boxes []int) int {
+	b.visit++
+	for _, p := range boxes {
+		b.visited[p] = b.visit
+	}
+	first := player
+	b.visited[player] = b.visit
+	queue := append(b.queue[:0], player)
+	for i := 0; i < len(queue); i++ {
+		p := queue[i]
+		if p < first {
+			first = p
+		}
+		for _, c := range courses {
+			to := p + b.delta(c)
+			if b.visited[to] == b.visit || b.walls[to] {
+				continue
+			}
+			b.visited[to] = b.visit
+			queue = append(queue, to)
+		}
+	}
+	b.queue = queue
+	return first
+}
+
+// walk returns the shortest path between two cells without pushing.
+func (b *board) walk(from, to int, boxes []int) []gokoban.Course {
+	if from == to {
+		return nil
+	}
+	prev := make([]int, len(b.walls))
+	for p := range prev {
+		prev[p] = -1
+	}
+	prev[from] = from
+	queue := []int{from}
+	for len(queue) > 0 && prev[to] < 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, c := range courses {
+			n := p + b.delta(c)
+			if prev[n] >= 0 || b.walls[n] || hasBox(boxes, n) {
+				continue
+			}
+			prev[n] = p
+			queue = append(queue, n)
+		}
+	}
+
+	var path []gokoban.Course
+	for p := to; p != from; p = prev[p] {
+		path = append(path, b.course(prev[p], p))
+	}
+	reverse(path)
+	return path
+}
+
+func (b *board) course(from, to int) gokoban.Course {
+	for _, c := range courses {
+		if from+b.delta(c) == to {
+			return c
+		}
+	}
+	return gokoban.Left
+}
+
+func reverse(path []gokoban.Course) {
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+}
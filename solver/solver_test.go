@@ -0,0 +1,150 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"github.com/x-cellent/gokoban/gokoban"
+	"strings"
+	"testing"
+	"time"
+)
+
+const turnLevel = "######\n#@   #\n# $  #\n#   .#\n######"
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		mode   Mode
+		moves  int
+		pushes int
+	}{
+		{name: "corridor moves", input: "#######\n#@ $ .#\n#######", mode: MoveOptimal, moves: 3, pushes: 2},
+		{name: "corridor pushes", input: "#######\n#@ $ .#\n#######", mode: PushOptimal, moves: 3, pushes: 2},
+		{name: "turn moves", input: turnLevel, mode: MoveOptimal, moves: 6, pushes: 3},
+		{name: "turn pushes", input: turnLevel, mode: PushOptimal, pushes: 3},
+		{name: "turn fast", input: turnLevel, mode: Fast},
+		{name: "two boxes moves", input: "#######\n#@$ . #\n# $ . #\n#     #\n#######", mode: MoveOptimal, moves: 7, pushes: 4},
+		{name: "two boxes pushes", input: "#######\n#@$ . #\n# $ . #\n#     #\n#######", mode: PushOptimal, pushes: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := loadLevel(t, tt.input)
+			solution, err := Solve(context.Background(), l, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := l.VerifySolution(solution)
+			if !v.Valid {
+				t.Fatalf("Solve() = %q fails at step %d: %v", l.FormatLURD(solution), v.Step, v.Err)
+			}
+			if tt.moves > 0 && v.Moves != tt.moves {
+				t.Errorf("Solve() = %q with %d moves, want %d", l.FormatLURD(solution), v.Moves, tt.moves)
+			}
+			if tt.pushes > 0 && v.Pushes != tt.pushes {
+				t.Errorf("Solve() = %q with %d pushes, want %d", l.FormatLURD(solution), v.Pushes, tt.pushes)
+			}
+			if l.MoveCount() != 0 {
+				t.Errorf("Solve() made %d moves on the level", l.MoveCount())
+			}
+		})
+	}
+}
+
+func TestSolveUnsolvable(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "box on dead square", input: "#######\n#  $  #\n#@   .#\n#######"},
+		{name: "boxes jammed in corridor", input: "#########\n#@ $ $..#\n#########"},
+		{name: "two boxes for one reachable target", input: "########\n#@ $$ .#\n#  ##  #\n#.##   #\n########"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, mode := range []Mode{MoveOptimal, PushOptimal, Fast} {
+				if _, err := Solve(context.Background(), loadLevel(t, tt.input), mode); err != ErrUnsolvable {
+					t.Errorf("Solve(%v) error = %v, want %v", mode, err, ErrUnsolvable)
+				}
+			}
+		})
+	}
+}
+
+func TestSolveCurrentAndInitial(t *testing.T) {
+	l := loadLevel(t, "########\n#@ $  .#\n########")
+	l.Move(gokoban.Right)
+
+	solution, err := Solve(context.Background(), l, MoveOptimal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(solution), 3; got != want {
+		t.Errorf("Solve() = %d moves, want %d", got, want)
+	}
+
+	solution, err = SolveInitial(context.Background(), l, MoveOptimal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := l.VerifySolution(solution); !v.Valid || v.Moves != 4 {
+		t.Errorf("SolveInitial() = %q, want 4 valid moves", l.FormatLURD(solution))
+	}
+
+	for _, c := range []gokoban.Course{gokoban.Right, gokoban.Right, gokoban.Right} {
+		l.Move(c)
+	}
+	solution, err = Solve(context.Background(), l, PushOptimal)
+	if err != nil || len(solution) != 0 {
+		t.Errorf("Solve() of a completed level = %v, %v, want no moves", solution, err)
+	}
+}
+
+func TestSolveContext(t *testing.T) {
+	l := loadLevel(t, turnLevel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Solve(ctx, l, PushOptimal); !errors.Is(err, context.Canceled) {
+		t.Errorf("Solve() error = %v, want %v", err, context.Canceled)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if _, err := Solve(ctx, l, Fast); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Solve() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func loadLevel(t *testing.T, input string) *gokoban.Level {
+	t.Helper()
+	l, err := gokoban.LoadLevel(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestSolveBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget int
+		want   error
+	}{
+		{name: "unlimited", budget: 0},
+		{name: "enough", budget: 100},
+		{name: "exceeded", budget: 1, want: ErrBudgetExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := loadLevel(t, "#######\n#@$ . #\n# $ . #\n#     #\n#######")
+			solution, err := SolveBudget(context.Background(), l, PushOptimal, tt.budget)
+			if err != tt.want {
+				t.Fatalf("SolveBudget() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && !l.VerifySolution(solution).Valid {
+				t.Errorf("SolveBudget() = %q is not valid", l.FormatLURD(solution))
+			}
+		})
+	}
+}